@@ -0,0 +1,101 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// AdminHandler returns a http.Handler to inspect and change logging at runtime.
+//
+// GET returns the global level, all levels set with SetLevel, the known
+// logger names with their effective level and the stats of the cores
+// registered with NamedCore, other cores don't show up:
+//
+//	curl localhost:8080/log
+//
+// PUT sets a level, an empty name sets the global level:
+//
+//	curl -X PUT localhost:8080/log -d '{"name": "db.*", "level": "debug"}'
+//
+// DELETE unsets a level:
+//
+//	curl -X DELETE localhost:8080/log?name=db.*
+//
+// The handler doesn't do any authentication, mount it accordingly.
+func AdminHandler() http.Handler {
+	return http.HandlerFunc(serveAdmin)
+}
+
+type adminLogger struct {
+	Name  string        `json:"name"`
+	Level zapcore.Level `json:"level"`
+}
+
+type adminState struct {
+	Level   zapcore.Level            `json:"level"`
+	Levels  map[string]zapcore.Level `json:"levels"`
+	Loggers []adminLogger            `json:"loggers"`
+	Cores   []CoreStats              `json:"cores"`
+}
+
+type adminRequest struct {
+	Name  string         `json:"name"`
+	Level *zapcore.Level `json:"level"`
+}
+
+func serveAdmin(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		// just return state below
+
+	case http.MethodPut, http.MethodPost:
+		req := adminRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			adminError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+			return
+		}
+		if req.Level == nil {
+			adminError(w, http.StatusBadRequest, "missing level")
+			return
+		}
+		SetLevel(req.Name, *req.Level)
+
+	case http.MethodDelete:
+		name := r.URL.Query().Get("name")
+		if normalizeLevelName(name) == "" {
+			adminError(w, http.StatusBadRequest, "global level can't be unset")
+			return
+		}
+		UnsetLevel(name)
+
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST, DELETE")
+		adminError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	state := adminState{
+		Level:   Level(""),
+		Levels:  Levels(),
+		Loggers: make([]adminLogger, 0),
+		Cores:   Cores(),
+	}
+
+	for _, name := range LoggerNames() {
+		state.Loggers = append(state.Loggers, adminLogger{Name: name, Level: Level(name)})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(state)
+}
+
+func adminError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
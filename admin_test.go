@@ -0,0 +1,119 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type errorCore struct {
+	zapcore.LevelEnabler
+}
+
+func (c *errorCore) With([]zapcore.Field) zapcore.Core { return c }
+func (c *errorCore) Sync() error                       { return nil }
+
+func (c *errorCore) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checkedEntry.AddCore(entry, c)
+	}
+	return checkedEntry
+}
+
+func (c *errorCore) Write(zapcore.Entry, []zapcore.Field) error {
+	return errors.New("remote unavailable")
+}
+
+func TestAdminHandler(t *testing.T) {
+	setTestLevels(t, zapcore.InfoLevel)
+
+	core := NamedCore("broken", &errorCore{zapcore.InfoLevel})
+	logger := zap.New(core, NamedLevels())
+	logger.Named("db").Info("hello")
+	logger.Named("db").Debug("dropped by core level")
+
+	h := AdminHandler()
+
+	// set level
+	{
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("PUT", "/", strings.NewReader(`{"name": "db.*", "level": "debug"}`)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %v: %v", rec.Code, rec.Body)
+		}
+		if Level("db.pool") != zapcore.DebugLevel {
+			t.Errorf("expected debug level for db.pool")
+		}
+	}
+
+	// get state
+	{
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+		state := adminState{}
+		if err := json.NewDecoder(rec.Body).Decode(&state); err != nil {
+			t.Fatal(err)
+		}
+
+		if state.Level != zapcore.InfoLevel || state.Levels["db.*"] != zapcore.DebugLevel {
+			t.Errorf("unexpected levels %v %v", state.Level, state.Levels)
+		}
+		if len(state.Loggers) != 1 || state.Loggers[0].Name != "db" || state.Loggers[0].Level != zapcore.DebugLevel {
+			t.Errorf("unexpected loggers %v", state.Loggers)
+		}
+
+		var broken *CoreStats
+		for i := range state.Cores {
+			if state.Cores[i].Name == "broken" {
+				broken = &state.Cores[i]
+			}
+		}
+		if broken == nil || broken.Writes != 1 || broken.Errors != 1 || broken.LastError != "remote unavailable" {
+			t.Errorf("unexpected core stats %+v", state.Cores)
+		}
+	}
+
+	// unset level
+	{
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("DELETE", "/?name=db.*", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %v: %v", rec.Code, rec.Body)
+		}
+		if Level("db.pool") != zapcore.InfoLevel {
+			t.Errorf("expected global level for db.pool")
+		}
+	}
+
+	// invalid level
+	{
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("PUT", "/", strings.NewReader(`{"level": "loud"}`)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %v", rec.Code)
+		}
+	}
+}
+
+func TestUnregisterCore(t *testing.T) {
+	prev := namedCores
+	namedCores = nil
+	t.Cleanup(func() { namedCores = prev })
+
+	NamedCore("old", zapcore.NewNopCore())
+	NamedCore("new", zapcore.NewNopCore())
+
+	if !UnregisterCore("old") || UnregisterCore("old") {
+		t.Errorf("expected core to be unregistered once")
+	}
+	if stats := Cores(); len(stats) != 1 || stats[0].Name != "new" {
+		t.Errorf("unexpected cores %+v", stats)
+	}
+}
@@ -0,0 +1,308 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// OverflowPolicy decides what happens to entries logged while the queue is full.
+type OverflowPolicy string
+
+const (
+	// OverflowBlock blocks the logging goroutine until there is room in the queue.
+	OverflowBlock OverflowPolicy = "block"
+
+	// OverflowDropNewest drops the entry being logged.
+	OverflowDropNewest OverflowPolicy = "dropNewest"
+
+	// OverflowDropOldest drops the oldest entry in the queue.
+	OverflowDropOldest OverflowPolicy = "dropOldest"
+
+	// OverflowDropBelowLevel drops the entry being logged, unless it's
+	// at KeepLevel, which blocks like OverflowBlock.
+	OverflowDropBelowLevel OverflowPolicy = "dropBelowLevel"
+)
+
+type AsyncConfig struct {
+	// QueueSize is the number of entries waiting to be written.
+	QueueSize int
+
+	// Workers is the number of goroutines writing to the wrapped core.
+	// Entries are written out of order with more than one worker.
+	Workers int
+
+	Overflow OverflowPolicy
+
+	// KeepLevel is used by OverflowDropBelowLevel.
+	KeepLevel zapcore.LevelEnabler
+}
+
+func NewAsyncConfig() AsyncConfig {
+	return AsyncConfig{
+		QueueSize: 1000,
+		Workers:   1,
+		Overflow:  OverflowDropBelowLevel,
+		KeepLevel: zap.NewAtomicLevelAt(zap.ErrorLevel),
+	}
+}
+
+// Build wraps core with Async and starts its workers.
+func (cfg AsyncConfig) Build(core zapcore.Core) (*Async, error) {
+	if cfg.QueueSize <= 0 {
+		return nil, fmt.Errorf("invalid QueueSize")
+	}
+	if cfg.Workers <= 0 {
+		return nil, fmt.Errorf("invalid Workers")
+	}
+	switch cfg.Overflow {
+	case OverflowBlock, OverflowDropNewest, OverflowDropOldest:
+	case OverflowDropBelowLevel:
+		if cfg.KeepLevel == nil {
+			return nil, fmt.Errorf("missing KeepLevel")
+		}
+	default:
+		return nil, fmt.Errorf("invalid Overflow %q", cfg.Overflow)
+	}
+
+	q := &asyncQueue{
+		overflow: cfg.Overflow,
+		keep:     cfg.KeepLevel,
+		items:    make([]asyncItem, cfg.QueueSize),
+	}
+	q.cond = sync.NewCond(&q.mu)
+
+	q.workers.Add(cfg.Workers)
+	for i := 0; i < cfg.Workers; i++ {
+		go q.work()
+	}
+
+	return &Async{Core: core, q: q}, nil
+}
+
+// Async is a core that writes entries to the wrapped core in the
+// background, so that slow cores, like remote services, don't stall
+// the logging goroutine. Sync waits until the queue is drained.
+//
+// Fields are written after the log call returned, so values
+// like zap.Object must not be changed afterwards.
+// Panic and fatal entries are written synchronously after
+// draining the queue, since the program is about to crash.
+//
+//	c := log.NewAsyncConfig()
+//	c.Overflow = log.OverflowDropOldest
+//	async, _ := c.Build(errorReportingCore)
+//	defer async.Close()
+type Async struct {
+	zapcore.Core
+
+	q *asyncQueue // shared with clones
+}
+
+// AsyncStats are the counts of an Async core since it was built.
+type AsyncStats struct {
+	Depth    int    `json:"depth"`    // entries in the queue
+	Capacity int    `json:"capacity"` // QueueSize
+	Written  uint64 `json:"written"`
+	Dropped  uint64 `json:"dropped"`
+	Errors   uint64 `json:"errors"`
+}
+
+type asyncItem struct {
+	core   zapcore.Core // the wrapped core with its context fields
+	entry  zapcore.Entry
+	fields []zapcore.Field
+}
+
+type asyncQueue struct {
+	overflow OverflowPolicy
+	keep     zapcore.LevelEnabler
+
+	mu      sync.Mutex
+	cond    *sync.Cond // signals any change of the queue
+	items   []asyncItem
+	head    int // index of the oldest item
+	len     int
+	active  int // items being written
+	closed  bool
+	err     error // last write error since the last Sync
+	failed  int   // write errors since the last Sync
+	workers sync.WaitGroup
+
+	written, dropped, errors uint64
+}
+
+func (a *Async) With(fields []zapcore.Field) zapcore.Core {
+	return &Async{
+		Core: a.Core.With(fields),
+		q:    a.q,
+	}
+}
+
+func (a *Async) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if a.Core.Check(entry, nil) != nil {
+		return checkedEntry.AddCore(entry, a)
+	}
+	return checkedEntry
+}
+
+func (a *Async) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	if entry.Level >= zapcore.DPanicLevel {
+		err := a.q.drain()
+		return multierr.Append(err, a.Core.Write(entry, fields))
+	}
+
+	// fields might be reused by the caller
+	item := asyncItem{
+		core:   a.Core,
+		entry:  entry,
+		fields: append([]zapcore.Field(nil), fields...),
+	}
+	if !a.q.push(item) {
+		return a.Core.Write(entry, fields) // closed, don't lose the entry
+	}
+	return nil
+}
+
+// Sync waits until all queued entries are written and syncs the wrapped core.
+// It returns the last error of writes since the last Sync, see Stats for all.
+func (a *Async) Sync() error {
+	return multierr.Append(a.q.drain(), a.Core.Sync())
+}
+
+// Close writes all queued entries, stops the workers and closes the wrapped
+// core, if it implements Close() error. Entries logged afterwards are
+// written synchronously.
+func (a *Async) Close() error {
+	a.q.mu.Lock()
+	a.q.closed = true
+	a.q.cond.Broadcast()
+	a.q.mu.Unlock()
+	a.q.workers.Wait()
+
+	err := multierr.Append(a.q.takeErr(), a.Core.Sync())
+	if c, ok := a.Core.(closer); ok {
+		err = multierr.Append(err, c.Close())
+	}
+	return err
+}
+
+// Stats returns the counts of the queue.
+func (a *Async) Stats() AsyncStats {
+	a.q.mu.Lock()
+	defer a.q.mu.Unlock()
+	return AsyncStats{
+		Depth:    a.q.len,
+		Capacity: len(a.q.items),
+		Written:  a.q.written,
+		Dropped:  a.q.dropped,
+		Errors:   a.q.errors,
+	}
+}
+
+// push adds item to the queue and applies the overflow policy.
+// It returns false, if the queue is closed.
+func (q *asyncQueue) push(item asyncItem) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for !q.closed && q.len == len(q.items) {
+		switch q.overflow {
+		case OverflowDropNewest:
+			q.dropped++
+			return true
+
+		case OverflowDropOldest:
+			q.pop()
+			q.dropped++
+
+		case OverflowDropBelowLevel:
+			if !q.keep.Enabled(item.entry.Level) {
+				q.dropped++
+				return true
+			}
+			q.cond.Wait()
+
+		default:
+			q.cond.Wait()
+		}
+	}
+
+	if q.closed {
+		return false
+	}
+
+	q.items[(q.head+q.len)%len(q.items)] = item
+	q.len++
+	q.cond.Broadcast()
+	return true
+}
+
+// pop removes the oldest item. The caller must hold q.mu.
+func (q *asyncQueue) pop() asyncItem {
+	item := q.items[q.head]
+	q.items[q.head] = asyncItem{}
+	q.head = (q.head + 1) % len(q.items)
+	q.len--
+	return item
+}
+
+func (q *asyncQueue) work() {
+	defer q.workers.Done()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		for q.len == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if q.len == 0 {
+			return // closed and drained
+		}
+
+		item := q.pop()
+		q.active++
+		q.cond.Broadcast()
+		q.mu.Unlock()
+
+		err := item.core.Write(item.entry, item.fields)
+
+		q.mu.Lock()
+		q.active--
+		if err != nil {
+			q.errors++
+			q.err = err
+			q.failed++
+		} else {
+			q.written++
+		}
+		q.cond.Broadcast()
+	}
+}
+
+// drain waits until the queue is empty and returns the last error since the last call.
+func (q *asyncQueue) drain() error {
+	q.mu.Lock()
+	for q.len > 0 || q.active > 0 {
+		q.cond.Wait()
+	}
+	q.mu.Unlock()
+	return q.takeErr()
+}
+
+// takeErr returns and resets the last write error, with
+// the number of errors before it, if there were more.
+func (q *asyncQueue) takeErr() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	err, failed := q.err, q.failed
+	q.err, q.failed = nil, 0
+	if failed > 1 {
+		return fmt.Errorf("%w (and %d earlier write errors)", err, failed-1)
+	}
+	return err
+}
@@ -0,0 +1,171 @@
+package log
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// gatedCore blocks writes until gate receives or is closed.
+type gatedCore struct {
+	zapcore.Core
+	gate chan struct{}
+}
+
+func (c *gatedCore) With(fields []zapcore.Field) zapcore.Core {
+	return &gatedCore{Core: c.Core.With(fields), gate: c.gate}
+}
+
+func (c *gatedCore) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checkedEntry.AddCore(entry, c)
+	}
+	return checkedEntry
+}
+
+func (c *gatedCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	<-c.gate
+	return c.Core.Write(entry, fields)
+}
+
+func TestAsync(t *testing.T) {
+	core, obs := observer.New(zapcore.InfoLevel)
+
+	a, err := NewAsyncConfig().Build(core)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.New(a).With(zap.String("service", "api"))
+
+	fields := []zap.Field{zap.Int("i", 1)}
+	logger.Info("hello", fields...)
+	fields[0] = zap.Int("i", 2) // reused by the caller
+	logger.Debug("not enabled")
+
+	if err := logger.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	logs := obs.TakeAll()
+	if len(logs) != 1 {
+		t.Fatalf("expected one entry, got %v", logs)
+	}
+	if ctx := logs[0].ContextMap(); ctx["service"] != "api" || ctx["i"] != int64(1) {
+		t.Errorf("unexpected fields %v", ctx)
+	}
+
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("after close")
+	if obs.Len() != 1 {
+		t.Errorf("expected entry after close to be written synchronously")
+	}
+	if s := a.Stats(); s.Written != 1 || s.Depth != 0 || s.Capacity != 1000 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestAsyncOverflow(t *testing.T) {
+	tests := []struct {
+		overflow OverflowPolicy
+		expect   []string
+		dropped  uint64
+	}{
+		{OverflowDropNewest, []string{"1", "2", "3"}, 2},
+		{OverflowDropOldest, []string{"1", "info", "error"}, 2},
+		{OverflowDropBelowLevel, []string{"1", "2", "3", "error"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.overflow), func(t *testing.T) {
+			core, obs := observer.New(zapcore.InfoLevel)
+			gated := &gatedCore{Core: core, gate: make(chan struct{})}
+
+			c := NewAsyncConfig()
+			c.QueueSize = 2
+			c.Overflow = tt.overflow
+			a, err := c.Build(gated)
+			if err != nil {
+				t.Fatal(err)
+			}
+			logger := zap.New(a)
+
+			logger.Info("1")
+
+			// wait for the worker to block on "1"
+			deadline := time.Now().Add(time.Second)
+			for a.Stats().Depth > 0 && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+
+			logger.Info("2")
+			logger.Info("3")
+			logger.Info("info")
+
+			done := make(chan struct{})
+			go func() {
+				logger.Error("error") // blocks with OverflowDropBelowLevel
+				close(done)
+			}()
+			if tt.overflow != OverflowDropBelowLevel {
+				<-done
+			}
+			close(gated.gate)
+			<-done
+
+			if err := logger.Sync(); err != nil {
+				t.Fatal(err)
+			}
+			if msgs := messages(obs); !reflect.DeepEqual(msgs, tt.expect) {
+				t.Errorf("expected %v, got %v", tt.expect, msgs)
+			}
+			if s := a.Stats(); s.Dropped != tt.dropped {
+				t.Errorf("expected %v dropped, got %+v", tt.dropped, s)
+			}
+		})
+	}
+}
+
+func TestAsyncErrors(t *testing.T) {
+	a, err := NewAsyncConfig().Build(&errorCore{zapcore.InfoLevel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.New(a)
+	logger.Info("hello")
+
+	if err := logger.Sync(); err == nil || err.Error() != "remote unavailable" {
+		t.Errorf("expected write error on Sync, got %v", err)
+	}
+	if err := logger.Sync(); err != nil {
+		t.Errorf("expected errors to be reset, got %v", err)
+	}
+	if s := a.Stats(); s.Errors != 1 {
+		t.Errorf("expected one error, got %+v", s)
+	}
+
+	for i := 0; i < 3; i++ {
+		logger.Info("hello")
+	}
+	if err := logger.Sync(); err == nil || err.Error() != "remote unavailable (and 2 earlier write errors)" {
+		t.Errorf("expected last write error, got %v", err)
+	}
+}
+
+func TestAsyncConfigBuild(t *testing.T) {
+	c := NewAsyncConfig()
+	c.Overflow = "random"
+	if _, err := c.Build(zapcore.NewNopCore()); err == nil {
+		t.Error("expected error for invalid overflow policy")
+	}
+
+	c = NewAsyncConfig()
+	c.QueueSize = 0
+	if _, err := c.Build(zapcore.NewNopCore()); err == nil {
+		t.Error("expected error for invalid queue size")
+	}
+}
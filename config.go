@@ -0,0 +1,306 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+)
+
+// CoreBuilder builds a zapcore.Core. It is implemented by the Config
+// types of the subpackages, i.e. slack.Config.
+type CoreBuilder interface {
+	Build() (zapcore.Core, error)
+}
+
+var (
+	outputs   = make(map[string]func() CoreBuilder)
+	outputsMu sync.RWMutex
+)
+
+// RegisterOutput makes an output kind available to config files.
+// newConfig must return a pointer to a config with default values,
+// which is then decoded from the output's section.
+// Registering the same kind twice replaces the previous registration.
+func RegisterOutput(kind string, newConfig func() CoreBuilder) {
+	outputsMu.Lock()
+	outputs[kind] = newConfig
+	outputsMu.Unlock()
+}
+
+// RegisteredOutputs returns the sorted list of registered output kinds.
+func RegisteredOutputs() []string {
+	outputsMu.RLock()
+	defer outputsMu.RUnlock()
+
+	kinds := make([]string, 0, len(outputs))
+	for k := range outputs {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func init() {
+	RegisterOutput("console", func() CoreBuilder {
+		c := NewConsoleOutputConfig()
+		return &c
+	})
+	RegisterOutput("file", func() CoreBuilder {
+		c := NewFileOutputConfig()
+		return &c
+	})
+}
+
+// FileConfig describes the whole logging setup of a service.
+// It is usually loaded with LoadConfig from a YAML or JSON file like:
+//
+//	sampling:
+//	  initial: 100
+//	  thereafter: 100
+//	stacktraceLevel: error
+//	outputs:
+//	  console:
+//	    level: info
+//	  googleStackdriver:
+//	    level: info
+//	    logID: my-service.v2
+//	  alerts:
+//	    type: slack
+//	    level: error
+//	    webhookURL: https://hooks.slack.com/services/xxx
+//
+// Each output section is named after its kind, unless a type is given.
+// Subpackages register their kind when imported, i.e.
+//
+//	import _ "github.com/mattes/log/slack"
+type FileConfig struct {
+	// DisableCaller stops annotating logs with the calling function's file name and line number.
+	DisableCaller bool `json:"disableCaller" yaml:"disableCaller"`
+
+	// StacktraceLevel records a stacktrace for logs at or above this level.
+	// By default, no stacktraces are recorded.
+	StacktraceLevel *zapcore.Level `json:"stacktraceLevel" yaml:"stacktraceLevel"`
+
+	// Sampling sets a sampling policy. A nil SamplingConfig disables sampling.
+	Sampling *zap.SamplingConfig `json:"sampling" yaml:"sampling"`
+
+	// ErrorOutputPaths is a list of URLs to write internal logger errors to.
+	ErrorOutputPaths []string `json:"errorOutputPaths" yaml:"errorOutputPaths"`
+
+	// Outputs are the decoded output sections in the order of the file.
+	Outputs []ConfigOutput `json:"-" yaml:"-"`
+}
+
+// ConfigOutput is a named output section of a FileConfig.
+type ConfigOutput struct {
+	Name   string
+	Kind   string
+	Config CoreBuilder
+}
+
+// LoadConfig reads a YAML or JSON config file.
+func LoadConfig(path string) (FileConfig, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return FileConfig{}, err
+	}
+
+	// JSON is valid YAML, except for tabs which
+	// JSON only allows as whitespace outside of strings.
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		body = bytes.ReplaceAll(body, []byte("\t"), []byte(" "))
+	}
+
+	cfg, err := ParseConfig(body)
+	if err != nil {
+		return FileConfig{}, fmt.Errorf("%v: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// ParseConfig parses a YAML or JSON config.
+func ParseConfig(body []byte) (FileConfig, error) {
+	cfg := FileConfig{}
+	if err := yaml.Unmarshal(body, &cfg); err != nil {
+		return FileConfig{}, err
+	}
+	return cfg, nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (cfg *FileConfig) UnmarshalYAML(value *yaml.Node) error {
+	type plain FileConfig // avoid recursion
+	raw := struct {
+		*plain  `yaml:",inline"`
+		Outputs yaml.Node `yaml:"outputs"`
+	}{plain: (*plain)(cfg)}
+
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+
+	if raw.Outputs.Kind == 0 {
+		return nil // no outputs
+	}
+
+	if raw.Outputs.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %v: outputs must be a map", raw.Outputs.Line)
+	}
+
+	// mapping node content alternates between keys and values
+	for i := 0; i+1 < len(raw.Outputs.Content); i += 2 {
+		o, err := decodeOutput(raw.Outputs.Content[i].Value, raw.Outputs.Content[i+1])
+		if err != nil {
+			return err
+		}
+		cfg.Outputs = append(cfg.Outputs, o)
+	}
+
+	return nil
+}
+
+func decodeOutput(name string, node *yaml.Node) (ConfigOutput, error) {
+	o := ConfigOutput{Name: name, Kind: name}
+
+	if node.Kind != yaml.MappingNode && node.ShortTag() != "!!null" {
+		return o, fmt.Errorf("line %v: output %v must be a map", node.Line, name)
+	}
+
+	// pick and remove type key, it's not part of the output's config
+	if node.Kind == yaml.MappingNode {
+		content := make([]*yaml.Node, 0, len(node.Content))
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "type" {
+				o.Kind = node.Content[i+1].Value
+				continue
+			}
+			content = append(content, node.Content[i], node.Content[i+1])
+		}
+		node.Content = content
+	}
+
+	outputsMu.RLock()
+	newConfig, ok := outputs[o.Kind]
+	outputsMu.RUnlock()
+	if !ok {
+		return o, fmt.Errorf("line %v: unknown output type %q, forgot to import it?", node.Line, o.Kind)
+	}
+
+	o.Config = newConfig()
+
+	// an empty section (i.e. "console:") keeps the defaults
+	if node.Kind == yaml.MappingNode {
+		if err := node.Decode(o.Config); err != nil {
+			return o, fmt.Errorf("output %v: %v", name, err)
+		}
+	}
+
+	return o, nil
+}
+
+// Build builds all outputs and returns a logger that tees into them.
+// Each output is registered with its name, see NamedCore, and levels set
+// with SetLevel apply on top of the outputs' levels.
+// The logger skips one caller and is meant to be passed to Use.
+func (cfg FileConfig) Build() (*zap.Logger, error) {
+	if len(cfg.Outputs) == 0 {
+		return nil, fmt.Errorf("missing outputs")
+	}
+
+	cores := make([]zapcore.Core, 0, len(cfg.Outputs))
+	for _, o := range cfg.Outputs {
+		core, err := o.Config.Build()
+		if err != nil {
+			return nil, fmt.Errorf("output %v: %v", o.Name, err)
+		}
+		cores = append(cores, NamedCore(o.Name, core))
+	}
+
+	opts := []zap.Option{
+		NamedLevels(),
+		zap.WithCaller(!cfg.DisableCaller),
+		zap.AddCallerSkip(1),
+	}
+
+	if cfg.StacktraceLevel != nil {
+		opts = append(opts, zap.AddStacktrace(*cfg.StacktraceLevel))
+	}
+
+	if len(cfg.ErrorOutputPaths) > 0 {
+		w, _, err := zap.Open(cfg.ErrorOutputPaths...)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, zap.ErrorOutput(w))
+	}
+
+	if cfg.Sampling != nil {
+		opts = append(opts, Sampling(cfg.Sampling.Initial, cfg.Sampling.Thereafter))
+	}
+
+	return zap.New(zapcore.NewTee(cores...), opts...), nil
+}
+
+// OutputConfig configures an output that encodes logs and writes them
+// to a list of paths, see zap.Open. It's registered as "console" and "file" output.
+type OutputConfig struct {
+	// Level is the minimum enabled logging level.
+	Level zap.AtomicLevel `json:"level" yaml:"level"`
+
+	// Encoding sets the encoder, i.e. console, json or logfmt.
+	Encoding string `json:"encoding" yaml:"encoding"`
+
+	// Paths is a list of URLs or file paths to write logs to.
+	Paths []string `json:"paths" yaml:"paths"`
+}
+
+// NewConsoleOutputConfig returns a config that writes
+// human-readable logs to stderr.
+func NewConsoleOutputConfig() OutputConfig {
+	return OutputConfig{
+		Level:    zap.NewAtomicLevelAt(zap.DebugLevel),
+		Encoding: "console",
+		Paths:    []string{"stderr"},
+	}
+}
+
+// NewFileOutputConfig returns a config that writes JSON logs.
+// Paths must be set.
+func NewFileOutputConfig() OutputConfig {
+	return OutputConfig{
+		Level:    zap.NewAtomicLevelAt(zap.InfoLevel),
+		Encoding: "json",
+	}
+}
+
+func (cfg OutputConfig) Build() (zapcore.Core, error) {
+	if len(cfg.Paths) == 0 {
+		return nil, fmt.Errorf("missing Paths")
+	}
+
+	encoderConfig := NewDevelopmentEncoderConfig()
+	if cfg.Encoding != "console" {
+		encoderConfig = zap.NewProductionEncoderConfig()
+	}
+	plainLevelsUnlessTerminal(&encoderConfig, cfg.Paths)
+
+	logger, err := zap.Config{
+		Level:         cfg.Level,
+		Encoding:      cfg.Encoding,
+		EncoderConfig: encoderConfig,
+		OutputPaths:   cfg.Paths,
+	}.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	return logger.Core(), nil
+}
@@ -0,0 +1,115 @@
+package log
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type testOutputConfig struct {
+	Level zap.AtomicLevel `yaml:"level"`
+	Name  string          `yaml:"name"`
+
+	obs *observer.ObservedLogs
+}
+
+func (cfg *testOutputConfig) Build() (zapcore.Core, error) {
+	core, obs := observer.New(cfg.Level)
+	cfg.obs = obs
+	return core, nil
+}
+
+func init() {
+	RegisterOutput("test", func() CoreBuilder {
+		return &testOutputConfig{Level: zap.NewAtomicLevelAt(zap.InfoLevel)}
+	})
+}
+
+func TestLoadConfig(t *testing.T) {
+	yamlConfig := `
+stacktraceLevel: error
+sampling:
+  initial: 10
+  thereafter: 10
+outputs:
+  test:
+    name: all
+  errors:
+    type: test
+    level: error
+    name: errors
+`
+
+	jsonConfig := `{
+	"stacktraceLevel": "error",
+	"sampling": {"initial": 10, "thereafter": 10},
+	"outputs": {
+		"test": {"name": "all"},
+		"errors": {"type": "test", "level": "error", "name": "errors"}
+	}
+}`
+
+	for file, body := range map[string]string{"log.yaml": yamlConfig, "log.json": jsonConfig} {
+		path := filepath.Join(t.TempDir(), file)
+		if err := ioutil.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(cfg.Outputs) != 2 {
+			t.Fatalf("%v: expected 2 outputs, got %v", file, len(cfg.Outputs))
+		}
+
+		all := cfg.Outputs[0].Config.(*testOutputConfig)
+		errs := cfg.Outputs[1].Config.(*testOutputConfig)
+
+		if all.Name != "all" || all.Level.Level() != zapcore.InfoLevel {
+			t.Errorf("%v: unexpected output config %+v", file, all)
+		}
+		if cfg.Outputs[1].Name != "errors" || cfg.Outputs[1].Kind != "test" ||
+			errs.Name != "errors" || errs.Level.Level() != zapcore.ErrorLevel {
+			t.Errorf("%v: unexpected output %+v", file, cfg.Outputs[1])
+		}
+
+		logger, err := cfg.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		logger.Info("hello")
+		logger.Error("oh no")
+
+		if all.obs.Len() != 2 {
+			t.Errorf("%v: expected 2 logs, got %v", file, all.obs.Len())
+		}
+		if errs.obs.Len() != 1 || errs.obs.All()[0].Entry.Stack == "" {
+			t.Errorf("%v: expected one error log with stacktrace, got %v", file, errs.obs.All())
+		}
+	}
+}
+
+func TestParseConfigUnknownOutput(t *testing.T) {
+	if _, err := ParseConfig([]byte("outputs:\n  foobar:\n")); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestParseConfigDefaults(t *testing.T) {
+	cfg, err := ParseConfig([]byte("outputs:\n  console:\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := cfg.Outputs[0].Config.(*OutputConfig)
+	if c.Encoding != "console" || len(c.Paths) != 1 || c.Paths[0] != "stderr" {
+		t.Errorf("expected console defaults, got %+v", c)
+	}
+}
@@ -0,0 +1,79 @@
+package log
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type contextKey struct{}
+
+// WithContext returns a copy of ctx that carries the given loosely typed
+// key-value pairs (see zap.SugaredLogger.With). Fields already attached to
+// ctx are kept, so it can be called at every layer of the call stack.
+func WithContext(ctx context.Context, args ...interface{}) context.Context {
+	if len(args) == 0 {
+		return ctx
+	}
+
+	parent := contextArgs(ctx)
+
+	// copy parent fields, so sibling contexts don't share the backing array
+	fields := make([]interface{}, 0, len(parent)+len(args))
+	fields = append(fields, parent...)
+	fields = append(fields, args...)
+
+	return context.WithValue(ctx, contextKey{}, fields)
+}
+
+// FromContext returns a logger that logs with all fields attached to ctx
+// via WithContext. It falls back to the default logger if ctx carries nothing.
+func FromContext(ctx context.Context) *zap.SugaredLogger {
+	// the default logger skips one caller for the funcs in this package,
+	// the returned logger is used directly though
+	return contextLogger(ctx).Desugar().WithOptions(zap.AddCallerSkip(-1)).Sugar()
+}
+
+// contextLogger returns the default logger with the fields from ctx.
+func contextLogger(ctx context.Context) *zap.SugaredLogger {
+	if args := contextArgs(ctx); len(args) > 0 {
+		return defaultSugarLogger().With(args...)
+	}
+	return defaultSugarLogger()
+}
+
+func contextArgs(ctx context.Context) []interface{} {
+	if ctx == nil {
+		return nil
+	}
+	args, _ := ctx.Value(contextKey{}).([]interface{})
+	return args
+}
+
+func DPanicCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	contextLogger(ctx).DPanicw(msg, keysAndValues...)
+}
+
+func DebugCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	contextLogger(ctx).Debugw(msg, keysAndValues...)
+}
+
+func ErrorCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	contextLogger(ctx).Errorw(msg, keysAndValues...)
+}
+
+func FatalCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	contextLogger(ctx).Fatalw(msg, keysAndValues...)
+}
+
+func InfoCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	contextLogger(ctx).Infow(msg, keysAndValues...)
+}
+
+func PanicCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	contextLogger(ctx).Panicw(msg, keysAndValues...)
+}
+
+func WarnCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	contextLogger(ctx).Warnw(msg, keysAndValues...)
+}
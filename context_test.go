@@ -0,0 +1,50 @@
+package log
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	obs := setTestLogger()
+
+	ctx := WithContext(context.Background(), "requestID", "req123")
+	ctx = WithContext(ctx, "userID", "user123")
+
+	InfoCtx(ctx, "hello", "foo", "bar")
+	FromContext(ctx).Info("world")
+
+	logs := obs.TakeAll()
+	if len(logs) != 2 {
+		t.Fatalf("expected 2 logs, got %v", len(logs))
+	}
+
+	for _, l := range logs {
+		fields := l.ContextMap()
+		if fields["requestID"] != "req123" || fields["userID"] != "user123" {
+			t.Errorf("expected context fields, got %v", fields)
+		}
+		if filepath.Base(l.Caller.File) != "context_test.go" {
+			t.Errorf("expected caller in context_test.go, got %v", l.Caller.File)
+		}
+	}
+
+	if logs[0].ContextMap()["foo"] != "bar" {
+		t.Errorf("expected foo=bar, got %v", logs[0].ContextMap())
+	}
+}
+
+func TestContextFallback(t *testing.T) {
+	obs := setTestLogger()
+
+	InfoCtx(context.Background(), "hello")
+
+	logs := obs.TakeAll()
+	if len(logs) != 1 {
+		t.Fatalf("expected 1 log, got %v", len(logs))
+	}
+	if len(logs[0].Context) != 0 {
+		t.Errorf("expected no fields, got %v", logs[0].Context)
+	}
+}
@@ -0,0 +1,245 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	namedCores   []*namedCoreStats
+	namedCoresMu sync.RWMutex
+)
+
+// NamedCore wraps core, so that it shows up with its name in Cores and the
+// AdminHandler. The wrapper counts writes and errors returned by Write and
+// Sync, which is where the cores of the subpackages report internal errors.
+// Registering a name again replaces the previous core, cores that aren't
+// used anymore should be removed with UnregisterCore. Cores that weren't
+// registered don't show up in Cores and aren't closed by Shutdown.
+//
+// core shouldn't be a tee of multiple cores, wrap each of them instead.
+// Outputs of a FileConfig are wrapped automatically.
+func NamedCore(name string, core zapcore.Core) zapcore.Core {
+	s := &namedCoreStats{name: name}
+	s.core = &namedCore{Core: core, stats: s}
+
+	namedCoresMu.Lock()
+	defer namedCoresMu.Unlock()
+
+	for i := range namedCores {
+		if namedCores[i].name == name {
+			namedCores[i] = s
+			return s.core
+		}
+	}
+
+	namedCores = append(namedCores, s)
+	return s.core
+}
+
+// UnregisterCore removes the core registered with name, i.e. after the
+// logger using it was replaced. It doesn't show up in Cores anymore and
+// isn't closed by Shutdown. It reports if a core was registered.
+func UnregisterCore(name string) bool {
+	namedCoresMu.Lock()
+	defer namedCoresMu.Unlock()
+
+	for i := range namedCores {
+		if namedCores[i].name == name {
+			namedCores = append(namedCores[:i], namedCores[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// CoreStats are the stats of a core registered with NamedCore.
+type CoreStats struct {
+	Name      string    `json:"name"`
+	Writes    int64     `json:"writes"`
+	Errors    int64     `json:"errors"`
+	LastError string    `json:"lastError,omitempty"`
+	LastErrAt time.Time `json:"lastErrorAt,omitempty"`
+}
+
+// Cores returns the stats of all cores registered with NamedCore,
+// unnamed cores aren't known.
+func Cores() []CoreStats {
+	namedCoresMu.RLock()
+	defer namedCoresMu.RUnlock()
+
+	stats := make([]CoreStats, 0, len(namedCores))
+	for _, s := range namedCores {
+		stats = append(stats, s.stats())
+	}
+	return stats
+}
+
+type namedCoreStats struct {
+	name string
+	core *namedCore // without context fields, for Shutdown
+
+	writes atomic.Int64
+	errors atomic.Int64
+
+	closeOnce sync.Once
+	closed    atomic.Bool
+
+	mu        sync.Mutex
+	lastErr   error
+	lastErrAt time.Time
+}
+
+func (s *namedCoreStats) record(err error) {
+	if err == nil {
+		return
+	}
+
+	s.errors.Inc()
+	s.mu.Lock()
+	s.lastErr = err
+	s.lastErrAt = time.Now()
+	s.mu.Unlock()
+}
+
+func (s *namedCoreStats) stats() CoreStats {
+	st := CoreStats{
+		Name:   s.name,
+		Writes: s.writes.Load(),
+		Errors: s.errors.Load(),
+	}
+
+	s.mu.Lock()
+	if s.lastErr != nil {
+		st.LastError = s.lastErr.Error()
+		st.LastErrAt = s.lastErrAt
+	}
+	s.mu.Unlock()
+
+	return st
+}
+
+type namedCore struct {
+	zapcore.Core
+	stats *namedCoreStats
+}
+
+func (c *namedCore) With(fields []zapcore.Field) zapcore.Core {
+	return &namedCore{Core: c.Core.With(fields), stats: c.stats}
+}
+
+func (c *namedCore) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checkWrapped(c.Core, entry, checkedEntry, c.write)
+}
+
+func (c *namedCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.write(entry, fields, c.Core.Write)
+}
+
+func (c *namedCore) write(entry zapcore.Entry, fields []zapcore.Field, next writeFunc) error {
+	c.stats.writes.Inc()
+	err := next(entry, fields)
+	c.stats.record(err)
+	return err
+}
+
+func (c *namedCore) Sync() error {
+	if c.stats.closed.Load() {
+		return nil // nothing left to flush, closing did
+	}
+	err := c.Core.Sync()
+	c.stats.record(err)
+	return err
+}
+
+// Close closes the wrapped core, if it implements Close() error.
+// Only the first call closes it, later ones and Sync return nil.
+func (c *namedCore) Close() (err error) {
+	cl, ok := c.Core.(closer)
+	if !ok {
+		return nil
+	}
+	c.stats.closeOnce.Do(func() {
+		c.stats.closed.Store(true)
+		err = cl.Close()
+		c.stats.record(err)
+	})
+	return err
+}
+
+// writeFunc writes an entry, like zapcore.Core's Write.
+type writeFunc func(entry zapcore.Entry, fields []zapcore.Field) error
+
+// wrappedWrite is the Write of a wrapper core, which calls next
+// to write the entry to the wrapped core, if at all.
+type wrappedWrite func(entry zapcore.Entry, fields []zapcore.Field, next writeFunc) error
+
+// checkWrapped is the Check of wrapper cores that change or count entries
+// in Write. It asks the wrapped core and if it writes the entry, write
+// will be called with a next func that writes to the cores that accepted
+// the entry. The wrapped core's Write can't be used, a tee writes to all
+// of its cores, whatever their level.
+func checkWrapped(core zapcore.Core, entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry, write wrappedWrite) *zapcore.CheckedEntry {
+	ce := core.Check(entry, nil)
+	if ce == nil {
+		return checkedEntry
+	}
+	return checkedEntry.AddCore(entry, &checkedCore{Core: core, ce: ce, write: write})
+}
+
+// checkedCore writes an entry checked by checkWrapped.
+type checkedCore struct {
+	zapcore.Core // the wrapped core, Write is the only method called
+	ce           *zapcore.CheckedEntry
+	write        wrappedWrite
+}
+
+func (c *checkedCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.write(entry, fields, c.next)
+}
+
+// next writes the entry to the cores of the checked entry, once.
+func (c *checkedCore) next(entry zapcore.Entry, fields []zapcore.Field) error {
+	ce := c.ce
+	if ce == nil {
+		return fmt.Errorf("entry was already written")
+	}
+	c.ce = nil
+	return writeChecked(ce, entry, fields)
+}
+
+// writeChecked writes entry to the cores of ce, which can't be used afterwards.
+// The cores report errors to ErrorOutput only, so they are returned as text.
+func writeChecked(ce *zapcore.CheckedEntry, entry zapcore.Entry, fields []zapcore.Field) error {
+	out := &errorOutput{}
+	ce.Entry = entry
+	ce.ErrorOutput = out
+	ce.Write(fields...)
+	return out.err
+}
+
+// errorOutput collects the write errors of a CheckedEntry.
+type errorOutput struct {
+	err error
+}
+
+func (o *errorOutput) Write(p []byte) (int, error) {
+	// written as "<time> write error: <err>\n"
+	msg := strings.TrimSuffix(string(p), "\n")
+	if i := strings.Index(msg, " write error: "); i >= 0 {
+		msg = msg[i+len(" write error: "):]
+	}
+	o.err = multierr.Append(o.err, errors.New(msg))
+	return len(p), nil
+}
+
+func (o *errorOutput) Sync() error {
+	return nil
+}
@@ -0,0 +1,216 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type DedupeConfig struct {
+	// Window is how long repeats of an entry are suppressed
+	// after it was logged.
+	Window time.Duration
+
+	// Fields are the keys of fields that make entries different,
+	// besides their level, logger name and message. Other fields are ignored.
+	Fields []string
+}
+
+func NewDedupeConfig() DedupeConfig {
+	return DedupeConfig{
+		Window: 10 * time.Second,
+	}
+}
+
+// Build wraps core with a Dedupe core.
+func (cfg DedupeConfig) Build(core zapcore.Core) (*Dedupe, error) {
+	if cfg.Window <= 0 {
+		return nil, fmt.Errorf("invalid Window")
+	}
+
+	return &Dedupe{
+		Core: core,
+		cfg:  cfg,
+		state: &dedupeState{
+			windows: make(map[string]*dedupeWindow),
+		},
+	}, nil
+}
+
+// Dedupe is a core that collapses repeated entries. The first entry is
+// written, repeats within the window are suppressed and once the window
+// closes, or the core is synced, a single entry like
+// "connection refused (repeated 42 times in 9.8s)" is written.
+//
+//	c := log.NewDedupeConfig()
+//	c.Fields = []string{"host"}
+//	d, err := c.Build(core)
+//	logger := zap.New(d)
+type Dedupe struct {
+	zapcore.Core
+
+	cfg     DedupeConfig
+	context []zapcore.Field // context fields with keys in cfg.Fields
+	state   *dedupeState    // shared with clones
+}
+
+type dedupeState struct {
+	mu      sync.Mutex
+	windows map[string]*dedupeWindow
+	queue   []*dedupeWindow // open windows, oldest first
+	timer   *time.Timer     // closes the oldest window, while there are any
+}
+
+type dedupeWindow struct {
+	key    string
+	first  time.Time
+	closes time.Time
+
+	// last repeat
+	core   zapcore.Core
+	entry  zapcore.Entry
+	fields []zapcore.Field
+	n      int
+}
+
+func (d *Dedupe) With(fields []zapcore.Field) zapcore.Core {
+	clone := &Dedupe{Core: d.Core.With(fields), cfg: d.cfg, state: d.state}
+	clone.context = append(clone.context, d.context...)
+	clone.context = append(clone.context, d.selected(fields)...)
+	return clone
+}
+
+func (d *Dedupe) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	// fields are only known in Write
+	return checkWrapped(d.Core, entry, checkedEntry, d.write)
+}
+
+func (d *Dedupe) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return d.write(entry, fields, d.Core.Write)
+}
+
+func (d *Dedupe) write(entry zapcore.Entry, fields []zapcore.Field, next writeFunc) error {
+	key := d.key(entry, fields)
+
+	d.state.mu.Lock()
+	if w, ok := d.state.windows[key]; ok {
+		w.core = d.Core
+		w.entry = entry
+		w.fields = append(w.fields[:0], fields...)
+		w.n++
+		d.state.mu.Unlock()
+		return nil
+	}
+
+	w := &dedupeWindow{key: key, first: entry.Time, closes: time.Now().Add(d.cfg.Window)}
+	d.state.windows[key] = w
+	d.state.queue = append(d.state.queue, w)
+	if d.state.timer == nil {
+		d.state.timer = time.AfterFunc(d.cfg.Window, d.state.sweep)
+	}
+	d.state.mu.Unlock()
+
+	return next(entry, fields)
+}
+
+// Sync writes the summaries of all open windows and syncs the wrapped core.
+func (d *Dedupe) Sync() error {
+	d.state.mu.Lock()
+	windows := d.state.queue
+	d.state.windows = make(map[string]*dedupeWindow)
+	d.state.queue = nil
+	if d.state.timer != nil {
+		d.state.timer.Stop()
+		d.state.timer = nil
+	}
+	d.state.mu.Unlock()
+
+	for _, w := range windows {
+		w.flush()
+	}
+
+	return d.Core.Sync()
+}
+
+// sweep closes the windows that are over, the timer is
+// reset for the next one. Windows close in order, since
+// they all have the same length.
+func (st *dedupeState) sweep() {
+	now := time.Now()
+
+	st.mu.Lock()
+	var closed []*dedupeWindow
+	for len(st.queue) > 0 && !st.queue[0].closes.After(now) {
+		w := st.queue[0]
+		st.queue[0] = nil
+		st.queue = st.queue[1:]
+		delete(st.windows, w.key)
+		closed = append(closed, w)
+	}
+
+	st.timer = nil
+	if len(st.queue) > 0 {
+		st.timer = time.AfterFunc(st.queue[0].closes.Sub(now), st.sweep)
+	}
+	st.mu.Unlock()
+
+	for _, w := range closed {
+		w.flush()
+	}
+}
+
+// flush writes the summary of a closed window, if there were repeats.
+func (w *dedupeWindow) flush() {
+	if w.n == 0 {
+		return
+	}
+
+	d := w.entry.Time.Sub(w.first).Round(time.Millisecond)
+	entry := w.entry
+	entry.Message = fmt.Sprintf("%v (repeated %d times in %v)", entry.Message, w.n, d)
+	if ce := w.core.Check(entry, nil); ce != nil {
+		ce.Write(append(w.fields, zap.Int("repeated", w.n))...)
+	}
+}
+
+// key identifies entries that are considered the same.
+func (d *Dedupe) key(entry zapcore.Entry, fields []zapcore.Field) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v\x00%v\x00%v", entry.Level, entry.LoggerName, entry.Message)
+
+	if len(d.cfg.Fields) == 0 {
+		return b.String()
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range d.context {
+		f.AddTo(enc)
+	}
+	for _, f := range d.selected(fields) {
+		f.AddTo(enc)
+	}
+	for _, k := range d.cfg.Fields {
+		if v, ok := enc.Fields[k]; ok {
+			fmt.Fprintf(&b, "\x00%v=%v", k, v)
+		}
+	}
+	return b.String()
+}
+
+// selected returns the fields with keys in cfg.Fields.
+func (d *Dedupe) selected(fields []zapcore.Field) []zapcore.Field {
+	var s []zapcore.Field
+	for _, f := range fields {
+		for _, k := range d.cfg.Fields {
+			if f.Key == k {
+				s = append(s, f)
+				break
+			}
+		}
+	}
+	return s
+}
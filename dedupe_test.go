@@ -0,0 +1,151 @@
+package log
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestDedupe(t *testing.T) {
+	core, obs := observer.New(zapcore.DebugLevel)
+
+	c := NewDedupeConfig()
+	c.Window = time.Hour
+	c.Fields = []string{"host"}
+	d, err := c.Build(core)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.New(d)
+	db := logger.With(zap.String("host", "db"))
+
+	for i := 0; i < 3; i++ {
+		db.Error("connection refused", zap.Int("attempt", i))
+		logger.Error("connection refused", zap.String("host", "cache"))
+		logger.Warn("connection refused")
+	}
+
+	expect := []string{"connection refused", "connection refused", "connection refused"}
+	if msgs := messages(obs); !reflect.DeepEqual(msgs, expect) {
+		t.Fatalf("expected %v, got %v", expect, msgs)
+	}
+
+	logger.Sync()
+	logs := obs.TakeAll()
+	if len(logs) != 3 {
+		t.Fatalf("expected three summaries, got %v", len(logs))
+	}
+
+	var found bool
+	for _, l := range logs {
+		ctx := l.ContextMap()
+		if ctx["host"] == "db" {
+			found = true
+			if ctx["repeated"] != int64(2) || ctx["attempt"] != int64(2) {
+				t.Errorf("unexpected summary fields %v", ctx)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("missing summary with context fields, got %v", logs)
+	}
+
+	// windows were closed
+	logger.Warn("connection refused")
+	if obs.Len() != 1 {
+		t.Errorf("expected entry after sync to be logged")
+	}
+}
+
+func TestDedupeWindow(t *testing.T) {
+	core, obs := observer.New(zapcore.DebugLevel)
+
+	c := NewDedupeConfig()
+	c.Window = 20 * time.Millisecond
+	d, err := c.Build(core)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.New(d)
+
+	logger.Error("down")
+	logger.Error("down")
+	logger.Error("down")
+
+	deadline := time.Now().Add(time.Second)
+	for obs.Len() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	logs := obs.TakeAll()
+	if len(logs) != 2 {
+		t.Fatalf("expected entry and summary, got %v", len(logs))
+	}
+	if msg := logs[1].Message; !strings.HasPrefix(msg, "down (repeated 2 times in ") {
+		t.Errorf("unexpected summary %q", msg)
+	}
+}
+
+func TestDedupeWindowsInOrder(t *testing.T) {
+	core, obs := observer.New(zapcore.DebugLevel)
+
+	c := NewDedupeConfig()
+	c.Window = 30 * time.Millisecond
+	d, err := c.Build(core)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.New(d)
+
+	logger.Error("a")
+	logger.Error("a")
+	time.Sleep(10 * time.Millisecond)
+	logger.Error("b")
+	logger.Error("b")
+
+	deadline := time.Now().Add(time.Second)
+	for obs.Len() < 4 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	msgs := messages(obs)
+	if len(msgs) != 4 || !strings.HasPrefix(msgs[2], "a (repeated 1 times") || !strings.HasPrefix(msgs[3], "b (repeated 1 times") {
+		t.Errorf("expected summaries in order, got %v", msgs)
+	}
+
+	d.state.mu.Lock()
+	defer d.state.mu.Unlock()
+	if len(d.state.windows) != 0 || len(d.state.queue) != 0 || d.state.timer != nil {
+		t.Errorf("expected no open windows and no timer")
+	}
+}
+
+func TestDedupeTee(t *testing.T) {
+	debug, debugObs := observer.New(zapcore.DebugLevel)
+	errs, errsObs := observer.New(zapcore.ErrorLevel)
+
+	d, err := NewDedupeConfig().Build(zapcore.NewTee(debug, errs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.New(d)
+
+	logger.Info("retrying")
+	logger.Info("retrying")
+	logger.Error("down")
+	logger.Error("down")
+	logger.Sync()
+
+	if n := debugObs.Len(); n != 4 {
+		t.Errorf("debug: expected entries and summaries, got %v", n)
+	}
+	msgs := messages(errsObs)
+	if len(msgs) != 2 || msgs[0] != "down" || !strings.HasPrefix(msgs[1], "down (repeated 1 times") {
+		t.Errorf("error: expected only error entry and its summary, got %v", msgs)
+	}
+}
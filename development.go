@@ -1,13 +1,24 @@
 package log
 
 import (
+	"os"
 	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+// NewDevelopmentEncoderConfig returns an encoder config with colored levels,
+// if stderr is a terminal and NO_COLOR is not set. Configs writing elsewhere
+// should use zapcore.CapitalLevelEncoder, the development encoder colors
+// keys and logger names only along with levels. NewEnvConfig and
+// OutputConfig take care of that.
 func NewDevelopmentEncoderConfig() zapcore.EncoderConfig {
+	encodeLevel := zapcore.CapitalLevelEncoder
+	if colorEnabled(os.Stderr) {
+		encodeLevel = zapcore.CapitalColorLevelEncoder
+	}
+
 	return zapcore.EncoderConfig{
 		TimeKey:        "T",
 		LevelKey:       "L",
@@ -16,7 +27,7 @@ func NewDevelopmentEncoderConfig() zapcore.EncoderConfig {
 		MessageKey:     "M",
 		StacktraceKey:  "S",
 		LineEnding:     zapcore.DefaultLineEnding,
-		EncodeLevel:    zapcore.CapitalColorLevelEncoder,
+		EncodeLevel:    encodeLevel,
 		EncodeTime:     timeEncoder,
 		EncodeDuration: zapcore.StringDurationEncoder,
 		EncodeCaller:   zapcore.ShortCallerEncoder,
@@ -37,7 +48,7 @@ func NewDevelopmentConfig() zap.Config {
 			Initial:    100,
 			Thereafter: 100,
 		},
-		Encoding:         "console",
+		Encoding:         "development",
 		EncoderConfig:    NewDevelopmentEncoderConfig(),
 		OutputPaths:      []string{"stderr"},
 		ErrorOutputPaths: []string{"stderr"},
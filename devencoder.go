@@ -0,0 +1,307 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// registered at variable initialization, before the default logger is set up in init
+var _ = zap.RegisterEncoder("development", func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	return NewDevelopmentEncoder(cfg), nil
+})
+
+var devPool = buffer.NewPool()
+
+// ANSI colors
+const (
+	colorKey    = "36" // cyan
+	colorLogger = "34" // blue
+	colorDim    = "90" // gray
+)
+
+// NewDevelopmentEncoder returns an encoder for humans looking at a terminal.
+// It's registered as "development" encoding and used by NewDevelopmentConfig.
+//
+// Like the console encoder it writes time, level, logger name, caller and
+// message separated by tabs, but fields are written as key=value. Nested
+// objects and multiline values, like errorVerbose, are pretty-printed on
+// the following lines, followed by the stacktrace.
+//
+// Keys and logger names are colored along with the level, if cfg.EncodeLevel
+// colors it, like NewDevelopmentEncoderConfig does for terminals.
+func NewDevelopmentEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	// fields are encoded as JSON first and then rewritten
+	fieldsConfig := zapcore.EncoderConfig{
+		LineEnding:     "\n",
+		EncodeTime:     cfg.EncodeTime,
+		EncodeDuration: cfg.EncodeDuration,
+		EncodeName:     cfg.EncodeName,
+	}
+	if fieldsConfig.EncodeTime == nil {
+		fieldsConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	}
+	if fieldsConfig.EncodeDuration == nil {
+		fieldsConfig.EncodeDuration = zapcore.StringDurationEncoder
+	}
+
+	return &devEncoder{
+		Encoder: zapcore.NewJSONEncoder(fieldsConfig),
+		cfg:     cfg,
+		color:   levelColored(cfg),
+	}
+}
+
+type devEncoder struct {
+	zapcore.Encoder // JSON encoder for context fields
+	cfg             zapcore.EncoderConfig
+	color           bool
+}
+
+func (e *devEncoder) Clone() zapcore.Encoder {
+	return &devEncoder{Encoder: e.Encoder.Clone(), cfg: e.cfg, color: e.color}
+}
+
+func (e *devEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	line := devPool.Get()
+
+	var cols []string
+	if e.cfg.TimeKey != "" && e.cfg.EncodeTime != nil {
+		arr := &stringArray{}
+		e.cfg.EncodeTime(entry.Time, arr)
+		cols = append(cols, e.paint(colorDim, arr.join()))
+	}
+	if e.cfg.LevelKey != "" && e.cfg.EncodeLevel != nil {
+		arr := &stringArray{}
+		e.cfg.EncodeLevel(entry.Level, arr)
+		cols = append(cols, arr.join())
+	}
+	if entry.LoggerName != "" && e.cfg.NameKey != "" {
+		name := entry.LoggerName
+		if e.cfg.EncodeName != nil {
+			arr := &stringArray{}
+			e.cfg.EncodeName(name, arr)
+			name = arr.join()
+		}
+		cols = append(cols, e.paint(colorLogger, name))
+	}
+	if entry.Caller.Defined && e.cfg.CallerKey != "" && e.cfg.EncodeCaller != nil {
+		arr := &stringArray{}
+		e.cfg.EncodeCaller(entry.Caller, arr)
+		cols = append(cols, e.paint(colorDim, arr.join()))
+	}
+	if e.cfg.MessageKey != "" {
+		cols = append(cols, entry.Message)
+	}
+	line.AppendString(strings.Join(cols, "\t"))
+
+	// encode context and fields as JSON, then rewrite them
+	enc := e.Encoder.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	js, err := enc.EncodeEntry(zapcore.Entry{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := e.writeFields(line, js.Bytes())
+	js.Free()
+	if err != nil {
+		return nil, err
+	}
+	line.AppendString(blocks)
+
+	if entry.Stack != "" && e.cfg.StacktraceKey != "" {
+		line.AppendByte('\n')
+		line.AppendString(e.prettyStack(entry.Stack))
+	}
+
+	if e.cfg.LineEnding != "" {
+		line.AppendString(e.cfg.LineEnding)
+	} else {
+		line.AppendString(zapcore.DefaultLineEnding)
+	}
+	return line, nil
+}
+
+// writeFields writes the scalar fields of a JSON object as key=value to line
+// and returns nested objects and multiline strings pretty-printed.
+func (e *devEncoder) writeFields(line *buffer.Buffer, js []byte) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader(js))
+	if _, err := dec.Token(); err != nil { // {
+		return "", err
+	}
+
+	var blocks strings.Builder
+	first := true
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return "", err
+		}
+		key, _ := t.(string)
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return "", err
+		}
+
+		switch raw[0] {
+		case '{', '[':
+			var b bytes.Buffer
+			if err := json.Indent(&b, raw, "    ", "  "); err != nil {
+				return "", err
+			}
+			fmt.Fprintf(&blocks, "\n    %v: %v", e.paint(colorKey, key), b.String())
+			continue
+
+		case '"':
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return "", err
+			}
+			if strings.Contains(s, "\n") {
+				s = strings.TrimRight(s, "\n")
+				fmt.Fprintf(&blocks, "\n    %v:\n      %v", e.paint(colorKey, key), strings.ReplaceAll(s, "\n", "\n      "))
+				continue
+			}
+			if s != "" && !strings.ContainsAny(s, " \t=\"") {
+				raw = json.RawMessage(s) // unquoted
+			}
+		}
+
+		if first {
+			line.AppendByte('\t')
+			first = false
+		} else {
+			line.AppendByte(' ')
+		}
+		line.AppendString(e.paint(colorKey, key))
+		line.AppendByte('=')
+		line.AppendString(string(raw))
+	}
+
+	return blocks.String(), nil
+}
+
+// prettyStack indents a zap stacktrace and dims its file:line lines.
+func (e *devEncoder) prettyStack(stack string) string {
+	lines := strings.Split(strings.TrimRight(stack, "\n"), "\n")
+	for i, l := range lines {
+		if strings.HasPrefix(l, "\t") {
+			lines[i] = "        " + e.paint(colorDim, strings.TrimPrefix(l, "\t"))
+		} else {
+			lines[i] = "    " + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *devEncoder) paint(color, s string) string {
+	if !e.color || s == "" {
+		return s
+	}
+	return "\x1b[" + color + "m" + s + "\x1b[0m"
+}
+
+// colorEnabled reports if f is a terminal and colors are not disabled
+// with NO_COLOR (https://no-color.org) or TERM=dumb.
+func colorEnabled(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(f)
+}
+
+// colorOutputs reports if all paths are stdout or stderr with colors enabled.
+func colorOutputs(paths []string) bool {
+	for _, p := range paths {
+		switch p {
+		case "stdout":
+			if !colorEnabled(os.Stdout) {
+				return false
+			}
+		case "stderr":
+			if !colorEnabled(os.Stderr) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return len(paths) > 0
+}
+
+// levelColored reports if cfg.EncodeLevel writes ANSI colors.
+func levelColored(cfg zapcore.EncoderConfig) bool {
+	if cfg.EncodeLevel == nil {
+		return false
+	}
+	arr := &stringArray{}
+	cfg.EncodeLevel(zapcore.InfoLevel, arr)
+	return strings.Contains(arr.join(), "\x1b[")
+}
+
+// plainLevelsUnlessTerminal drops colored levels, if cfg writes to files or pipes.
+func plainLevelsUnlessTerminal(cfg *zapcore.EncoderConfig, paths []string) {
+	if levelColored(*cfg) && !colorOutputs(paths) {
+		cfg.EncodeLevel = zapcore.CapitalLevelEncoder
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// hostname is part of the file URLs of HyperlinkCallerEncoder.
+var hostname, _ = os.Hostname()
+
+// HyperlinkCallerEncoder encodes the caller like zapcore.ShortCallerEncoder,
+// but as OSC-8 hyperlink to the file, which many terminals make clickable.
+// Terminals without support usually show the caller as text.
+func HyperlinkCallerEncoder(caller zapcore.EntryCaller, enc zapcore.PrimitiveArrayEncoder) {
+	path := filepath.ToSlash(caller.File)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	enc.AppendString("\x1b]8;;file://" + hostname + path + "\x1b\\" + caller.TrimmedPath() + "\x1b]8;;\x1b\\")
+}
+
+// stringArray collects the output of time, level, name and caller encoders.
+type stringArray []string
+
+func (a *stringArray) join() string { return strings.Join(*a, " ") }
+
+func (a *stringArray) AppendBool(v bool)              { a.append(v) }
+func (a *stringArray) AppendByteString(v []byte)      { a.append(string(v)) }
+func (a *stringArray) AppendComplex128(v complex128)  { a.append(v) }
+func (a *stringArray) AppendComplex64(v complex64)    { a.append(v) }
+func (a *stringArray) AppendFloat64(v float64)        { a.append(formatFloat(v, 64)) }
+func (a *stringArray) AppendFloat32(v float32)        { a.append(formatFloat(float64(v), 32)) }
+func (a *stringArray) AppendInt(v int)                { a.append(v) }
+func (a *stringArray) AppendInt64(v int64)            { a.append(v) }
+func (a *stringArray) AppendInt32(v int32)            { a.append(v) }
+func (a *stringArray) AppendInt16(v int16)            { a.append(v) }
+func (a *stringArray) AppendInt8(v int8)              { a.append(v) }
+func (a *stringArray) AppendString(v string)          { a.append(v) }
+func (a *stringArray) AppendUint(v uint)              { a.append(v) }
+func (a *stringArray) AppendUint64(v uint64)          { a.append(v) }
+func (a *stringArray) AppendUint32(v uint32)          { a.append(v) }
+func (a *stringArray) AppendUint16(v uint16)          { a.append(v) }
+func (a *stringArray) AppendUint8(v uint8)            { a.append(v) }
+func (a *stringArray) AppendUintptr(v uintptr)        { a.append(v) }
+func (a *stringArray) AppendDuration(v time.Duration) { a.append(v) }
+func (a *stringArray) AppendTime(v time.Time)         { a.append(v) }
+
+func (a *stringArray) append(v interface{}) {
+	*a = append(*a, fmt.Sprint(v))
+}
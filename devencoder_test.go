@@ -0,0 +1,118 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type testObject struct{}
+
+func (testObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("a", "b")
+	enc.AddInt("c", 1)
+	return nil
+}
+
+func TestDevelopmentEncoder(t *testing.T) {
+	cfg := NewDevelopmentEncoderConfig()
+	cfg.EncodeLevel = zapcore.CapitalLevelEncoder
+	enc := NewDevelopmentEncoder(cfg)
+	enc.(*devEncoder).color = false
+	enc.AddString("service", "api")
+
+	entry := zapcore.Entry{
+		Level:      zapcore.ErrorLevel,
+		Time:       time.Date(2021, 8, 1, 10, 11, 12, 0, time.UTC),
+		LoggerName: "db",
+		Message:    "query failed",
+		Caller:     zapcore.NewEntryCaller(0, "/src/app/db.go", 42, true),
+		Stack:      "main.query\n\t/src/app/db.go:42\nmain.main\n\t/src/app/main.go:10",
+	}
+	fields := []zapcore.Field{
+		zap.String("table", "users"),
+		zap.String("query", "select * from users"),
+		zap.Int("rows", 0),
+		zap.Object("obj", testObject{}),
+		zap.String("detail", "line 1\nline 2"),
+	}
+
+	buf, err := enc.EncodeEntry(entry, fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := "10:11:12\tERROR\tdb\tapp/db.go:42\tquery failed\tservice=api table=users query=\"select * from users\" rows=0\n" +
+		"    obj: {\n      \"a\": \"b\",\n      \"c\": 1\n    }\n" +
+		"    detail:\n      line 1\n      line 2\n" +
+		"    main.query\n        /src/app/db.go:42\n    main.main\n        /src/app/main.go:10\n"
+	if buf.String() != expect {
+		t.Errorf("expected\n%v\ngot\n%v", expect, buf.String())
+	}
+}
+
+func TestDevelopmentEncoderColor(t *testing.T) {
+	enc := NewDevelopmentEncoder(NewDevelopmentEncoderConfig())
+	enc.(*devEncoder).color = true
+
+	buf, err := enc.EncodeEntry(zapcore.Entry{LoggerName: "db", Message: "hello"}, []zapcore.Field{zap.Error(errors.New("failed"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "\x1b[34mdb\x1b[0m") || !strings.Contains(buf.String(), "\x1b[36merror\x1b[0m=failed") {
+		t.Errorf("expected colored logger name and key, got %q", buf.String())
+	}
+}
+
+func TestColorEnabled(t *testing.T) {
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	setEnv(t, "NO_COLOR", "1")
+	if colorEnabled(f) {
+		t.Errorf("expected no colors with NO_COLOR")
+	}
+
+	tmp, err := os.CreateTemp(t.TempDir(), "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tmp.Close()
+
+	setEnv(t, "NO_COLOR", "")
+	if colorEnabled(tmp) {
+		t.Errorf("expected no colors for regular files")
+	}
+	if colorOutputs([]string{"stderr", tmp.Name()}) {
+		t.Errorf("expected no colors for outputs including files")
+	}
+}
+
+func TestDevelopmentEncoderColorLevels(t *testing.T) {
+	cfg := NewDevelopmentEncoderConfig()
+	cfg.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	if !NewDevelopmentEncoder(cfg).(*devEncoder).color {
+		t.Errorf("expected colors along with colored levels")
+	}
+
+	cfg.EncodeLevel = zapcore.CapitalLevelEncoder
+	if NewDevelopmentEncoder(cfg).(*devEncoder).color {
+		t.Errorf("expected no colors along with plain levels")
+	}
+}
+
+func TestHyperlinkCallerEncoder(t *testing.T) {
+	arr := &stringArray{}
+	HyperlinkCallerEncoder(zapcore.NewEntryCaller(0, "/src/app/db.go", 42, true), arr)
+	if s := arr.join(); !strings.HasPrefix(s, "\x1b]8;;file://") || !strings.Contains(s, "/src/app/db.go\x1b\\app/db.go:42\x1b]8;;\x1b\\") {
+		t.Errorf("unexpected hyperlink %q", s)
+	}
+}
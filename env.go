@@ -0,0 +1,103 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Environment variables read by NewEnvConfig.
+const (
+	// EnvLevel sets the minimum enabled logging level, i.e. debug, info, warn, error.
+	EnvLevel = "LOG_LEVEL"
+
+	// EnvFormat sets the encoding, i.e. development, console, json or logfmt.
+	EnvFormat = "LOG_FORMAT"
+
+	// EnvOutput sets a comma separated list of output paths, i.e. stderr,/var/log/app.log
+	EnvOutput = "LOG_OUTPUT"
+
+	// EnvDevelopment switches between development (true) and production (false) config.
+	EnvDevelopment = "LOG_DEVELOPMENT"
+
+	// EnvFieldNaming sets the naming scheme of the entry keys, i.e. zap, ecs, otel or gcp.
+	EnvFieldNaming = "LOG_FIELD_NAMING"
+
+	// EnvHyperlinks makes callers clickable in terminals supporting OSC-8 hyperlinks, if true.
+	EnvHyperlinks = "LOG_HYPERLINKS"
+)
+
+// NewEnvConfig returns the development config, adjusted by environment variables.
+// Unset variables leave the config untouched.
+func NewEnvConfig() (zap.Config, error) {
+	cfg := NewDevelopmentConfig()
+
+	if v, ok := lookupEnv(EnvDevelopment); ok {
+		development, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("%v: %v", EnvDevelopment, err)
+		}
+		if !development {
+			cfg = NewProductionConfig()
+		}
+	}
+
+	if v, ok := lookupEnv(EnvLevel); ok {
+		if err := cfg.Level.UnmarshalText([]byte(v)); err != nil {
+			return cfg, fmt.Errorf("%v: %v", EnvLevel, err)
+		}
+	}
+
+	if v, ok := lookupEnv(EnvFormat); ok {
+		cfg.Encoding = strings.ToLower(v)
+
+		// colors only make sense for humans looking at a console
+		if cfg.Development && cfg.Encoding != "console" && cfg.Encoding != "development" {
+			cfg.EncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		}
+	}
+
+	if v, ok := lookupEnv(EnvFieldNaming); ok {
+		if err := FieldNaming(strings.ToLower(v)).Apply(&cfg); err != nil {
+			return cfg, fmt.Errorf("%v: %v", EnvFieldNaming, err)
+		}
+	}
+
+	if v, ok := lookupEnv(EnvHyperlinks); ok {
+		hyperlinks, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("%v: %v", EnvHyperlinks, err)
+		}
+		if hyperlinks {
+			cfg.EncoderConfig.EncodeCaller = HyperlinkCallerEncoder
+		}
+	}
+
+	if v, ok := lookupEnv(EnvOutput); ok {
+		cfg.OutputPaths = splitList(v)
+	}
+	plainLevelsUnlessTerminal(&cfg.EncoderConfig, cfg.OutputPaths)
+
+	return cfg, nil
+}
+
+// lookupEnv returns the trimmed value of an environment variable
+// and reports false if it is unset or empty.
+func lookupEnv(key string) (string, bool) {
+	v := strings.TrimSpace(os.Getenv(key))
+	return v, v != ""
+}
+
+func splitList(s string) []string {
+	l := make([]string, 0)
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			l = append(l, v)
+		}
+	}
+	return l
+}
@@ -0,0 +1,73 @@
+package log
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func setEnv(t *testing.T, key, value string) {
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Unsetenv(key) })
+}
+
+func TestNewEnvConfig(t *testing.T) {
+	setEnv(t, EnvDevelopment, "false")
+	setEnv(t, EnvLevel, "warn")
+	setEnv(t, EnvFormat, "JSON")
+	setEnv(t, EnvOutput, "stdout, /tmp/app.log")
+
+	cfg, err := NewEnvConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Development {
+		t.Error("expected production config")
+	}
+	if cfg.Level.Level() != zapcore.WarnLevel {
+		t.Errorf("expected warn level, got %v", cfg.Level.Level())
+	}
+	if cfg.Encoding != "json" {
+		t.Errorf("expected json encoding, got %v", cfg.Encoding)
+	}
+	if !reflect.DeepEqual(cfg.OutputPaths, []string{"stdout", "/tmp/app.log"}) {
+		t.Errorf("unexpected output paths %v", cfg.OutputPaths)
+	}
+}
+
+func TestNewEnvConfigDefaults(t *testing.T) {
+	cfg, err := NewEnvConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(cfg.OutputPaths, NewDevelopmentConfig().OutputPaths) ||
+		cfg.Encoding != "development" || cfg.Level.Level() != zapcore.DebugLevel {
+		t.Errorf("expected development config, got %+v", cfg)
+	}
+}
+
+func TestNewEnvConfigFileOutput(t *testing.T) {
+	setEnv(t, EnvOutput, "/tmp/app.log")
+
+	cfg, err := NewEnvConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if levelColored(cfg.EncoderConfig) {
+		t.Errorf("expected no colors for files")
+	}
+}
+
+func TestNewEnvConfigInvalid(t *testing.T) {
+	setEnv(t, EnvLevel, "loud")
+
+	if _, err := NewEnvConfig(); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,255 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Err is like zap.Error, but also writes the cause chain and stack of err, see NamedErr.
+func Err(err error) zap.Field {
+	return NamedErr("error", err)
+}
+
+// NamedErr is like zap.NamedError, but writes structured fields:
+//
+//	error:       the error message
+//	errorCauses: the chain of wrapped errors (errors.Unwrap), each with message and type
+//	errorStack:  the frames of the stack, if an error in the chain carries one, see StackOf
+//
+// The keys are prefixed with key instead of "error".
+func NamedErr(key string, err error) zap.Field {
+	if err == nil {
+		return zap.Skip()
+	}
+	f := zap.Inline(structuredError{key: key, err: err})
+	f.Key = key // ignored when encoding, but tells the field apart
+	return f
+}
+
+type structuredError struct {
+	key     string
+	err     error
+	noStack bool // see OmitErrorStacks
+}
+
+func (e structuredError) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString(e.key, e.err.Error())
+	if err := enc.AddArray(e.key+"Causes", errorChain{e.err}); err != nil {
+		return err
+	}
+	if e.noStack {
+		return nil
+	}
+	if frames := StackOf(e.err); len(frames) > 0 {
+		return enc.AddArray(e.key+"Stack", stackFrames(frames))
+	}
+	return nil
+}
+
+// errorType returns the type of err, or the type returned by its
+// ErrorType() string method, for errors that were deserialized.
+func errorType(err error) string {
+	if t, ok := err.(interface{ ErrorType() string }); ok {
+		return t.ErrorType()
+	}
+	return fmt.Sprintf("%T", err)
+}
+
+// errorChain marshals the chain of wrapped errors, outermost first.
+type errorChain struct {
+	err error
+}
+
+func (c errorChain) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for err := c.err; err != nil; err = errors.Unwrap(err) {
+		e := err
+		enc.AppendObject(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("type", errorType(e))
+			enc.AddString("message", e.Error())
+			return nil
+		}))
+	}
+	return nil
+}
+
+// StructuredErrors returns a zap.Option that wraps a core, so that all error
+// fields, i.e. from zap.Error or Errorw("msg", "error", err), are written like NamedErr.
+func StructuredErrors() zap.Option {
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &structuredErrorsCore{Core: core}
+	})
+}
+
+type structuredErrorsCore struct {
+	zapcore.Core
+}
+
+func (c *structuredErrorsCore) With(fields []zapcore.Field) zapcore.Core {
+	return &structuredErrorsCore{Core: c.Core.With(structureErrors(fields))}
+}
+
+func (c *structuredErrorsCore) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checkWrapped(c.Core, entry, checkedEntry, c.write)
+}
+
+func (c *structuredErrorsCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.write(entry, fields, c.Core.Write)
+}
+
+func (c *structuredErrorsCore) write(entry zapcore.Entry, fields []zapcore.Field, next writeFunc) error {
+	return next(entry, structureErrors(fields))
+}
+
+// structureErrors replaces error fields, fields is copied if anything changes.
+func structureErrors(fields []zapcore.Field) []zapcore.Field {
+	out := fields
+	copied := false
+	for i, f := range fields {
+		if f.Type != zapcore.ErrorType {
+			continue
+		}
+		err, ok := f.Interface.(error)
+		if !ok {
+			continue
+		}
+		if !copied {
+			out = append([]zapcore.Field(nil), fields...)
+			copied = true
+		}
+		out[i] = NamedErr(f.Key, err)
+	}
+	return out
+}
+
+// FieldError returns the error of fields created with
+// zap.Error, zap.NamedError, Err and NamedErr.
+func FieldError(f zapcore.Field) (error, bool) {
+	switch f.Type {
+	case zapcore.ErrorType:
+		err, ok := f.Interface.(error)
+		return err, ok && err != nil
+	case zapcore.InlineMarshalerType:
+		if e, ok := f.Interface.(structuredError); ok {
+			return e.err, true
+		}
+	}
+	return nil, false
+}
+
+// ErrorStack returns the stack of the first error field carrying one,
+// see StackOf, or nil.
+func ErrorStack(fields []zapcore.Field) []runtime.Frame {
+	for _, f := range fields {
+		if err, ok := FieldError(f); ok {
+			if frames := StackOf(err); len(frames) > 0 {
+				return frames
+			}
+		}
+	}
+	return nil
+}
+
+// FormatStack formats frames like runtime.Stack, which
+// services like Google Cloud Error Reporting expect.
+func FormatStack(frames []runtime.Frame) string {
+	var b strings.Builder
+	b.WriteString("goroutine 1 [running]:\n")
+	for _, f := range frames {
+		fmt.Fprintf(&b, "%s(...)\n\t%s:%d\n", f.Function, f.File, f.Line)
+	}
+	return b.String()
+}
+
+// OmitErrorStacks returns fields without the stacks of fields created with
+// Err and NamedErr, for cores that report the stack separately.
+// fields is copied if anything changes.
+func OmitErrorStacks(fields []zapcore.Field) []zapcore.Field {
+	out := fields
+	copied := false
+	for i, f := range fields {
+		e, ok := f.Interface.(structuredError)
+		if !ok || f.Type != zapcore.InlineMarshalerType || e.noStack {
+			continue
+		}
+		if !copied {
+			out = append([]zapcore.Field(nil), fields...)
+			copied = true
+		}
+		e.noStack = true
+		out[i].Interface = e
+	}
+	return out
+}
+
+// StackOf returns the stack of the innermost error in the chain of err
+// that carries one, or nil. Stacks are recorded by errors packages like
+// github.com/pkg/errors, which provide a StackTrace method, or packages
+// providing a Callers method. Both must return a slice of program counters.
+// Errors that were deserialized, i.e. by the spool package, can provide
+// their frames with a StackFrames() []runtime.Frame method instead.
+func StackOf(err error) []runtime.Frame {
+	var frames []runtime.Frame
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if f := ownStack(e); len(f) > 0 {
+			frames = f
+		}
+	}
+	return frames
+}
+
+// ownStack returns the stack carried by err itself, ignoring wrapped errors.
+func ownStack(err error) []runtime.Frame {
+	if s, ok := err.(interface{ StackFrames() []runtime.Frame }); ok {
+		return s.StackFrames()
+	}
+	if pcs := stackPCs(err); len(pcs) > 0 {
+		return collectFrames(pcs)
+	}
+	return nil
+}
+
+// stackPCs calls StackTrace() or Callers() on err, if they return a slice
+// of uintptr kind, i.e. errors.StackTrace of github.com/pkg/errors.
+func stackPCs(err error) []uintptr {
+	v := reflect.ValueOf(err)
+	for _, name := range []string{"StackTrace", "Callers"} {
+		m := v.MethodByName(name)
+		if !m.IsValid() {
+			continue
+		}
+
+		t := m.Type()
+		if t.NumIn() != 0 || t.NumOut() != 1 || t.Out(0).Kind() != reflect.Slice || t.Out(0).Elem().Kind() != reflect.Uintptr {
+			continue
+		}
+
+		out := m.Call(nil)[0]
+		pcs := make([]uintptr, out.Len())
+		for i := range pcs {
+			pcs[i] = uintptr(out.Index(i).Uint())
+		}
+		return pcs
+	}
+	return nil
+}
+
+type stackFrames []runtime.Frame
+
+func (s stackFrames) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, f := range s {
+		f := f
+		enc.AppendObject(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("function", f.Function)
+			enc.AddString("file", f.File)
+			enc.AddInt("line", f.Line)
+			return nil
+		}))
+	}
+	return nil
+}
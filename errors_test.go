@@ -0,0 +1,163 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// frame and stackError mimic github.com/pkg/errors
+type frame uintptr
+
+type stackError struct {
+	msg   string
+	stack []uintptr
+}
+
+func newStackError(msg string) error {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	return &stackError{msg: msg, stack: pcs[:n]}
+}
+
+func (e *stackError) Error() string { return e.msg }
+
+func (e *stackError) StackTrace() []frame {
+	f := make([]frame, len(e.stack))
+	for i := range e.stack {
+		f[i] = frame(e.stack[i])
+	}
+	return f
+}
+
+func TestErr(t *testing.T) {
+	core, obs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(core)
+
+	err := fmt.Errorf("save failed: %w", newStackError("disk full"))
+	logger.Error("oops", Err(err))
+
+	ctx := obs.TakeAll()[0].ContextMap()
+	if ctx["error"] != "save failed: disk full" {
+		t.Errorf("unexpected error %v", ctx["error"])
+	}
+
+	causes, _ := ctx["errorCauses"].([]interface{})
+	if len(causes) != 2 || causes[1].(map[string]interface{})["type"] != "*log.stackError" {
+		t.Errorf("unexpected causes %v", ctx["errorCauses"])
+	}
+
+	stack, _ := ctx["errorStack"].([]interface{})
+	if len(stack) == 0 || stack[0].(map[string]interface{})["function"] != "github.com/mattes/log.TestErr" {
+		t.Errorf("unexpected stack %v", ctx["errorStack"])
+	}
+}
+
+func TestStructuredErrors(t *testing.T) {
+	core, obs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(core, StructuredErrors()).Sugar()
+
+	logger.With("cause", errors.New("context")).Errorw("save failed", "error", errors.New("failed"))
+
+	ctx := obs.TakeAll()[0].ContextMap()
+	if ctx["error"] != "failed" || ctx["errorCauses"] == nil || ctx["causeCauses"] == nil {
+		t.Errorf("expected structured errors, got %v", ctx)
+	}
+	if _, ok := ctx["errorStack"]; ok {
+		t.Errorf("expected no stack for errors without stack")
+	}
+}
+
+func TestStructuredErrorsTee(t *testing.T) {
+	debug, debugObs := observer.New(zapcore.DebugLevel)
+	errs, errsObs := observer.New(zapcore.ErrorLevel)
+	logger := zap.New(zapcore.NewTee(debug, errs), StructuredErrors())
+
+	logger.Info("hello")
+	logger.Error("failed", zap.Error(errors.New("oh no")))
+
+	if debugObs.Len() != 2 {
+		t.Errorf("expected two entries in debug core, got %v", debugObs.Len())
+	}
+	logs := errsObs.TakeAll()
+	if len(logs) != 1 || logs[0].Message != "failed" || logs[0].ContextMap()["errorCauses"] == nil {
+		t.Errorf("expected only the structured error in error core, got %v", logs)
+	}
+}
+
+func TestCheckWrappedErrors(t *testing.T) {
+	core := NamedCore("broken", zapcore.NewTee(&errorCore{zapcore.InfoLevel}, zapcore.NewNopCore()))
+	t.Cleanup(func() { UnregisterCore("broken") })
+
+	ce := core.Check(zapcore.Entry{Level: zapcore.InfoLevel}, nil)
+	ce.ErrorOutput = zapcore.AddSync(&strings.Builder{})
+	ce.Write()
+
+	for _, s := range Cores() {
+		if s.Name == "broken" && (s.Errors != 1 || s.LastError != "remote unavailable") {
+			t.Errorf("expected write error in stats, got %+v", s)
+		}
+	}
+}
+
+func TestFieldError(t *testing.T) {
+	err := errors.New("failed")
+
+	for _, f := range []zapcore.Field{zap.Error(err), zap.NamedError("x", err), Err(err), NamedErr("x", err)} {
+		if e, ok := FieldError(f); !ok || e != err {
+			t.Errorf("%v: expected error", f.Key)
+		}
+	}
+	if _, ok := FieldError(zap.String("error", "failed")); ok {
+		t.Errorf("expected no error for string field")
+	}
+}
+
+func TestStackOf(t *testing.T) {
+	if StackOf(errors.New("no stack")) != nil {
+		t.Errorf("expected no stack")
+	}
+
+	frames := StackOf(fmt.Errorf("wrapped: %w", newStackError("inner")))
+	if len(frames) == 0 || frames[0].Function != "github.com/mattes/log.TestStackOf" {
+		t.Errorf("unexpected frames %v", frames)
+	}
+}
+
+func TestErrorStack(t *testing.T) {
+	frames := ErrorStack([]zapcore.Field{zap.String("a", "b"), Err(fmt.Errorf("wrapped: %w", newStackError("inner")))})
+	stack := FormatStack(frames)
+	if !strings.HasPrefix(stack, "goroutine 1 [running]:\ngithub.com/mattes/log.TestErrorStack(...)\n\t") {
+		t.Errorf("unexpected stack %q", stack)
+	}
+
+	if ErrorStack([]zapcore.Field{zap.Error(errors.New("no stack"))}) != nil {
+		t.Errorf("expected no stack")
+	}
+}
+
+func TestOmitErrorStacks(t *testing.T) {
+	fields := []zapcore.Field{Err(newStackError("inner"))}
+	omitted := OmitErrorStacks(fields)
+
+	enc := zapcore.NewMapObjectEncoder()
+	omitted[0].AddTo(enc)
+	if _, ok := enc.Fields["errorStack"]; ok || enc.Fields["errorCauses"] == nil {
+		t.Errorf("expected causes without stack, got %v", enc.Fields)
+	}
+	if len(ErrorStack(omitted)) == 0 {
+		t.Errorf("expected stack to be still available")
+	}
+
+	enc = zapcore.NewMapObjectEncoder()
+	fields[0].AddTo(enc)
+	if _, ok := enc.Fields["errorStack"]; !ok {
+		t.Errorf("expected fields to be unchanged")
+	}
+}
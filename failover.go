@@ -0,0 +1,340 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type FailoverConfig struct {
+	// Secondary receives the entries the primary core fails to write,
+	// i.e. a JSON core writing to stderr or a file core.
+	Secondary zapcore.Core
+
+	// Failures is the number of consecutive failed writes after which
+	// entries are diverted to the secondary core. Deferred errors, see
+	// DeferredError, are counted within Retry instead, since the writes
+	// in between succeed anyway.
+	Failures int
+
+	// Retry is the interval the primary core is tried again while failed over.
+	Retry time.Duration
+
+	// Healthy optionally reports the health of the primary core, i.e. errors
+	// of a remote client. It's called before every write to the primary core.
+	Healthy func() error
+}
+
+func NewFailoverConfig() FailoverConfig {
+	return FailoverConfig{
+		Failures: 3,
+		Retry:    30 * time.Second,
+	}
+}
+
+// Build wraps the primary core with Failover.
+func (cfg FailoverConfig) Build(primary zapcore.Core) (*Failover, error) {
+	if cfg.Secondary == nil {
+		return nil, fmt.Errorf("missing Secondary")
+	}
+	if cfg.Failures <= 0 {
+		return nil, fmt.Errorf("invalid Failures")
+	}
+	if cfg.Retry <= 0 {
+		return nil, fmt.Errorf("invalid Retry")
+	}
+
+	return &Failover{
+		Core:      primary,
+		secondary: cfg.Secondary,
+		state: &failoverState{
+			cfg: cfg,
+			now: time.Now,
+		},
+	}, nil
+}
+
+// Failover is a core that writes entries to a secondary core while the
+// primary core fails, i.e. a remote service is down. Entries the primary core
+// fails to write are written to the secondary core, so they aren't lost.
+// After Failures consecutive failures, entries go to the secondary core
+// directly and the primary core is retried every Retry interval.
+//
+// Once the primary core recovers, a summary of the outage is logged to it,
+// like `Failed over to secondary core for 2m30s`.
+//
+// Cores that write in the background, like googleStackdriver, report errors
+// of earlier writes. They return them wrapped with DeferredError, so the entry
+// just written isn't diverted, since the core accepted it. Failures of them
+// within Retry add up, even if writes in between return nil. Entries that
+// failed in the background can't be diverted, use the spool package to keep them.
+//
+//	c := log.NewFailoverConfig()
+//	c.Secondary = stderrCore
+//	f, err := c.Build(stackdriverCore)
+//	logger := zap.New(f)
+type Failover struct {
+	zapcore.Core // primary
+
+	secondary zapcore.Core
+	state     *failoverState // shared with clones
+}
+
+// FailoverStats describe the state of a Failover core.
+type FailoverStats struct {
+	FailedOver bool      `json:"failedOver"`
+	Since      time.Time `json:"since,omitempty"` // first failure of the current outage
+	Outages    int64     `json:"outages"`
+	Diverted   int64     `json:"diverted"` // entries written to the secondary core
+	LastError  string    `json:"lastError,omitempty"`
+}
+
+type failoverState struct {
+	cfg FailoverConfig
+	now func() time.Time
+
+	mu         sync.Mutex
+	failures   int // consecutive failures
+	deferred   int // deferred errors since deferredAt
+	deferredAt time.Time
+	failedOver bool
+	since      time.Time
+	retryAt    time.Time
+	diverted   int64 // entries diverted during the current outage
+	outages    int64
+	total      int64 // entries diverted since the core was built
+	lastErr    error
+}
+
+func (f *Failover) With(fields []zapcore.Field) zapcore.Core {
+	return &Failover{
+		Core:      f.Core.With(fields),
+		secondary: f.secondary.With(fields),
+		state:     f.state,
+	}
+}
+
+func (f *Failover) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if f.Core.Check(entry, nil) != nil {
+		return checkedEntry.AddCore(entry, f)
+	}
+	return checkedEntry
+}
+
+func (f *Failover) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	if !f.state.tryPrimary() {
+		return f.divert(entry, fields)
+	}
+
+	var err error
+	if f.state.cfg.Healthy != nil {
+		err = f.state.cfg.Healthy()
+	}
+	if err == nil {
+		err = f.Core.Write(entry, fields)
+	}
+
+	if err != nil {
+		if f.state.fail(err) {
+			f.writeFailedOver(err)
+		}
+		if IsDeferredError(err) {
+			return nil // the entry was accepted
+		}
+		return f.divert(entry, fields)
+	}
+
+	if out := f.state.recover(); out != nil {
+		f.writeSummary(*out)
+	}
+	return nil
+}
+
+// Sync syncs the primary and secondary core.
+func (f *Failover) Sync() error {
+	return multierr.Append(f.Core.Sync(), f.secondary.Sync())
+}
+
+// Close closes the primary and secondary core, if they implement Close() error.
+func (f *Failover) Close() error {
+	var err error
+	for _, c := range []zapcore.Core{f.Core, f.secondary} {
+		if cl, ok := c.(closer); ok {
+			err = multierr.Append(err, cl.Close())
+		}
+	}
+	return err
+}
+
+// Stats returns the state of the core.
+func (f *Failover) Stats() FailoverStats {
+	f.state.mu.Lock()
+	defer f.state.mu.Unlock()
+
+	s := FailoverStats{
+		FailedOver: f.state.failedOver,
+		Outages:    f.state.outages,
+		Diverted:   f.state.total,
+	}
+	if f.state.failedOver {
+		s.Since = f.state.since
+	}
+	if f.state.lastErr != nil {
+		s.LastError = f.state.lastErr.Error()
+	}
+	return s
+}
+
+func (f *Failover) divert(entry zapcore.Entry, fields []zapcore.Field) error {
+	f.state.mu.Lock()
+	f.state.diverted++
+	f.state.total++
+	f.state.mu.Unlock()
+
+	if f.secondary.Check(entry, nil) == nil {
+		return nil
+	}
+	return f.secondary.Write(entry, fields)
+}
+
+// outage is a summary entry to be written after unlocking failoverState
+type outage struct {
+	since, until time.Time
+	diverted     int64
+	err          error
+}
+
+// tryPrimary reports if an entry should be written to the primary core.
+func (st *failoverState) tryPrimary() bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if !st.failedOver {
+		return true
+	}
+	if now := st.now(); !now.Before(st.retryAt) {
+		st.retryAt = now.Add(st.cfg.Retry) // one entry retries, the others are diverted
+		return true
+	}
+	return false
+}
+
+// fail records a failed write and reports if the core just failed over.
+func (st *failoverState) fail(err error) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	now := st.now()
+	st.lastErr = err
+
+	n := 0
+	if IsDeferredError(err) {
+		// writes returning nil don't reset them, they were just accepted
+		if st.deferred > 0 && now.Sub(st.deferredAt) >= st.cfg.Retry {
+			st.deferred = 0
+		}
+		if st.deferred == 0 {
+			st.deferredAt = now
+		}
+		st.deferred++
+		n = st.deferred
+	} else {
+		if st.failures == 0 {
+			st.since = now
+		}
+		st.failures++
+		n = st.failures
+	}
+
+	if st.failedOver || n < st.cfg.Failures {
+		return false
+	}
+	if IsDeferredError(err) && (st.failures == 0 || st.deferredAt.Before(st.since)) {
+		st.since = st.deferredAt // the outage started with the first deferred error
+	}
+	st.failedOver = true
+	st.retryAt = now.Add(st.cfg.Retry)
+	st.outages++
+	return true
+}
+
+// recover records a successful write and returns the outage it ended, if any.
+func (st *failoverState) recover() *outage {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var out *outage
+	if st.failedOver {
+		out = &outage{since: st.since, until: st.now(), diverted: st.diverted, err: st.lastErr}
+	}
+	st.failures = 0
+	if st.failedOver {
+		st.deferred = 0
+	}
+	st.failedOver = false
+	st.diverted = 0
+	return out
+}
+
+func (f *Failover) writeFailedOver(err error) {
+	entry := zapcore.Entry{
+		Level:   zapcore.ErrorLevel,
+		Time:    f.state.now(),
+		Message: fmt.Sprintf("Primary core failed %d times, failing over to secondary core", f.state.cfg.Failures),
+	}
+	if ce := f.secondary.Check(entry, nil); ce != nil {
+		ce.Write(zap.Error(err), zap.Duration("retry", f.state.cfg.Retry))
+	}
+}
+
+// writeSummary writes the summary of an outage to the primary core,
+// with the context fields of f.
+func (f *Failover) writeSummary(out outage) {
+	d := out.until.Sub(out.since)
+	entry := zapcore.Entry{
+		Level:   zapcore.WarnLevel,
+		Time:    out.until,
+		Message: fmt.Sprintf("Failed over to secondary core for %v", d),
+	}
+
+	fields := []zapcore.Field{
+		zap.Time("failedSince", out.since),
+		zap.Time("recovered", out.until),
+		zap.Duration("outage", d),
+		zap.Int64("diverted", out.diverted),
+		zap.Error(out.err),
+	}
+	if ce := f.Core.Check(entry, nil); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+// DeferredError wraps errors that a core returns for earlier writes,
+// i.e. when it writes in the background, so that Failover doesn't
+// divert the entry being written. It returns nil if err is nil.
+// Wrappers like NamedCore return the errors of their cores as text,
+// so Failover should wrap the core returning them directly.
+func DeferredError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &deferredError{err}
+}
+
+// IsDeferredError reports if err was wrapped with DeferredError.
+func IsDeferredError(err error) bool {
+	var d *deferredError
+	return errors.As(err, &d)
+}
+
+type deferredError struct {
+	err error
+}
+
+func (e *deferredError) Error() string { return e.err.Error() }
+func (e *deferredError) Unwrap() error { return e.err }
@@ -0,0 +1,268 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// flakyCore fails writes while down is set.
+type flakyCore struct {
+	zapcore.Core
+
+	mu   *sync.Mutex
+	down *bool
+}
+
+func (c *flakyCore) With(fields []zapcore.Field) zapcore.Core {
+	return &flakyCore{Core: c.Core.With(fields), mu: c.mu, down: c.down}
+}
+
+func (c *flakyCore) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checkedEntry.AddCore(entry, c)
+	}
+	return checkedEntry
+}
+
+func (c *flakyCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if *c.down {
+		return errors.New("remote unavailable")
+	}
+	return c.Core.Write(entry, fields)
+}
+
+func (c *flakyCore) setDown(down bool) {
+	c.mu.Lock()
+	*c.down = down
+	c.mu.Unlock()
+}
+
+func TestFailover(t *testing.T) {
+	primaryCore, primaryObs := observer.New(zapcore.InfoLevel)
+	primary := &flakyCore{Core: primaryCore, mu: &sync.Mutex{}, down: new(bool)}
+	secondary, secondaryObs := observer.New(zapcore.InfoLevel)
+
+	c := NewFailoverConfig()
+	c.Secondary = secondary
+	c.Failures = 2
+	c.Retry = time.Minute
+	f, err := c.Build(primary)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.state.now = func() time.Time { return now }
+
+	logger := zap.New(f).With(zap.String("service", "api"))
+	logger.Info("1")
+	logger.Debug("not enabled")
+
+	primary.setDown(true)
+	logger.Info("2")
+	logger.Info("3") // fails over
+	now = now.Add(30 * time.Second)
+	logger.Info("4") // diverted without trying primary
+
+	if s := f.Stats(); !s.FailedOver || s.Diverted != 3 || s.Outages != 1 || s.LastError != "remote unavailable" {
+		t.Errorf("unexpected stats %+v", s)
+	}
+
+	primary.setDown(false)
+	logger.Info("5") // before retry
+	now = now.Add(time.Minute)
+	logger.Info("6") // retried and recovered
+
+	summary := primaryObs.FilterMessageSnippet("Failed over").All()
+	if len(summary) != 1 {
+		t.Fatalf("expected outage summary, got %v", primaryObs.All())
+	}
+	if ctx := summary[0].ContextMap(); ctx["diverted"] != int64(4) || ctx["error"] != "remote unavailable" || ctx["service"] != "api" {
+		t.Errorf("unexpected summary fields %v", ctx)
+	}
+	if msgs, expect := messages(primaryObs), []string{"1", "6", "Failed over to secondary core for 1m30s"}; !reflect.DeepEqual(msgs, expect) {
+		t.Errorf("primary: expected %v, got %v", expect, msgs)
+	}
+
+	logs := secondaryObs.TakeAll()
+	var msgs []string
+	for _, l := range logs {
+		msgs = append(msgs, l.Message)
+	}
+	if expect := []string{"2", "Primary core failed 2 times, failing over to secondary core", "3", "4", "5"}; !reflect.DeepEqual(msgs, expect) {
+		t.Errorf("secondary: expected %v, got %v", expect, msgs)
+	}
+	if ctx := logs[0].ContextMap(); ctx["service"] != "api" {
+		t.Errorf("expected context fields on secondary core, got %v", ctx)
+	}
+
+	if s := f.Stats(); s.FailedOver || s.Diverted != 4 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+// deferredCore writes entries, but fails in the background,
+// and reports the error with the next write.
+type deferredCore struct {
+	zapcore.Core
+}
+
+func (c *deferredCore) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checkedEntry.AddCore(entry, c)
+	}
+	return checkedEntry
+}
+
+func (c *deferredCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	c.Core.Write(entry, fields)
+	return DeferredError(errors.New("earlier write failed"))
+}
+
+func TestFailoverDeferredErrors(t *testing.T) {
+	primaryCore, primaryObs := observer.New(zapcore.InfoLevel)
+	secondary, secondaryObs := observer.New(zapcore.InfoLevel)
+
+	c := NewFailoverConfig()
+	c.Secondary = secondary
+	c.Failures = 2
+	f, err := c.Build(&deferredCore{primaryCore})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := zap.New(f)
+	logger.Info("1")
+	logger.Info("2") // fails over
+	logger.Info("3")
+
+	if msgs, expect := messages(primaryObs), []string{"1", "2"}; !reflect.DeepEqual(msgs, expect) {
+		t.Errorf("primary: expected %v, got %v", expect, msgs)
+	}
+	if msgs, expect := messages(secondaryObs), []string{"Primary core failed 2 times, failing over to secondary core", "3"}; !reflect.DeepEqual(msgs, expect) {
+		t.Errorf("secondary: expected %v, got %v", expect, msgs)
+	}
+
+	if !IsDeferredError(fmt.Errorf("wrapped: %w", DeferredError(errors.New("x")))) || IsDeferredError(errors.New("x")) {
+		t.Errorf("unexpected IsDeferredError")
+	}
+	if DeferredError(nil) != nil {
+		t.Errorf("expected nil")
+	}
+}
+
+// intermittentCore reports a deferred error with every other write.
+type intermittentCore struct {
+	zapcore.Core
+	n int
+}
+
+func (c *intermittentCore) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checkedEntry.AddCore(entry, c)
+	}
+	return checkedEntry
+}
+
+func (c *intermittentCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	c.Core.Write(entry, fields)
+	c.n++
+	if c.n%2 == 0 {
+		return DeferredError(errors.New("earlier write failed"))
+	}
+	return nil
+}
+
+func TestFailoverIntermittentDeferredErrors(t *testing.T) {
+	primaryCore, primaryObs := observer.New(zapcore.InfoLevel)
+	secondary, secondaryObs := observer.New(zapcore.InfoLevel)
+
+	c := NewFailoverConfig()
+	c.Secondary = secondary
+	c.Failures = 3
+	c.Retry = time.Minute
+	f, err := c.Build(&intermittentCore{Core: primaryCore})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.state.now = func() time.Time { return now }
+
+	logger := zap.New(f)
+	for i := 0; i < 6; i++ {
+		logger.Info("a") // 2, 4 and 6 report errors, 6 fails over
+	}
+	logger.Info("diverted")
+
+	if s := f.Stats(); !s.FailedOver || s.Outages != 1 || s.Diverted != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+	if n := primaryObs.Len(); n != 6 {
+		t.Errorf("expected 6 entries in primary, got %v", n)
+	}
+	if msgs := messages(secondaryObs); len(msgs) != 2 || msgs[1] != "diverted" {
+		t.Errorf("unexpected secondary %v", msgs)
+	}
+
+	// errors spread over more than Retry don't add up
+	f, err = c.Build(&intermittentCore{Core: primaryCore})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.state.now = func() time.Time { return now }
+	logger = zap.New(f)
+	for i := 0; i < 10; i++ {
+		logger.Info("a")
+		now = now.Add(20 * time.Second)
+	}
+	if s := f.Stats(); s.FailedOver || s.Outages != 0 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestFailoverHealthy(t *testing.T) {
+	primary, primaryObs := observer.New(zapcore.InfoLevel)
+	secondary, secondaryObs := observer.New(zapcore.InfoLevel)
+
+	c := NewFailoverConfig()
+	c.Secondary = secondary
+	c.Failures = 1
+	c.Healthy = func() error { return errors.New("client error") }
+	f, err := c.Build(primary)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zap.New(f).Info("hello")
+	if primaryObs.Len() != 0 {
+		t.Errorf("expected unhealthy primary core to be skipped")
+	}
+	if logs := secondaryObs.All(); len(logs) != 2 || !strings.HasPrefix(logs[0].Message, "Primary core failed") {
+		t.Errorf("unexpected secondary logs %v", logs)
+	}
+}
+
+func TestFailoverConfigBuild(t *testing.T) {
+	c := NewFailoverConfig()
+	if _, err := c.Build(zapcore.NewNopCore()); err == nil {
+		t.Error("expected error for missing secondary core")
+	}
+
+	c.Secondary = zapcore.NewNopCore()
+	c.Retry = 0
+	if _, err := c.Build(zapcore.NewNopCore()); err == nil {
+		t.Error("expected error for invalid retry interval")
+	}
+}
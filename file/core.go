@@ -0,0 +1,111 @@
+package file
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mattes/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type Config struct {
+	// Level is the minimum enabled logging level.
+	// By default, only >= info levels are logged.
+	Level zap.AtomicLevel `yaml:"level"`
+
+	// Encoding sets the encoder, either json, logfmt or console.
+	Encoding string `yaml:"encoding"`
+
+	// Path is the file logs are written to. Rotated files are kept in the
+	// same directory, named like my-service-2006-01-02T15-04-05.000.log
+	Path string `yaml:"path"`
+
+	// MaxBytes rotates the file before it grows larger than this size.
+	// Zero disables size based rotation.
+	MaxBytes int64 `yaml:"maxBytes"`
+
+	// Interval rotates the file when the interval changes, i.e. 24h rotates
+	// at midnight UTC. Zero disables time based rotation.
+	Interval time.Duration `yaml:"interval"`
+
+	// Compress gzips rotated files.
+	Compress bool `yaml:"compress"`
+
+	// MaxAge deletes rotated files older than this. Zero keeps them forever.
+	MaxAge time.Duration `yaml:"maxAge"`
+
+	// MaxBackups is the number of rotated files to keep. Zero keeps all of them.
+	MaxBackups int `yaml:"maxBackups"`
+
+	// ReopenOnSIGHUP closes and reopens the file on SIGHUP,
+	// for use with external tools like logrotate.
+	ReopenOnSIGHUP bool `yaml:"reopenOnSIGHUP"`
+}
+
+// register as "rotatingFile" output for log.LoadConfig
+func init() {
+	log.RegisterOutput("rotatingFile", func() log.CoreBuilder {
+		c := NewConfig()
+		return &c
+	})
+}
+
+func NewConfig() Config {
+	return Config{
+		Level:      zap.NewAtomicLevelAt(zap.InfoLevel),
+		Encoding:   "json",
+		MaxBytes:   100 << 20, // 100 MB
+		Compress:   true,
+		MaxBackups: 10,
+	}
+}
+
+func (cfg Config) Build() (zapcore.Core, error) {
+	var enc zapcore.Encoder
+	switch cfg.Encoding {
+	case "json":
+		enc = zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	case "logfmt":
+		enc = log.NewLogfmtEncoder(zap.NewProductionEncoderConfig())
+	case "console":
+		encoderConfig := log.NewDevelopmentEncoderConfig()
+		encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder // no colors in files
+		enc = zapcore.NewConsoleEncoder(encoderConfig)
+	default:
+		return nil, fmt.Errorf("unknown Encoding %q", cfg.Encoding)
+	}
+
+	w, err := cfg.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	return &core{
+		Core:   zapcore.NewCore(enc, w, cfg.Level),
+		writer: w,
+	}, nil
+}
+
+type core struct {
+	zapcore.Core
+	writer *Writer
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	return &core{Core: c.Core.With(fields), writer: c.writer}
+}
+
+func (c *core) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checkedEntry.AddCore(entry, c)
+	}
+
+	return checkedEntry
+}
+
+// Close closes the file, the core can't be used afterwards.
+// It's called by log.Shutdown.
+func (c *core) Close() error {
+	return c.writer.Close()
+}
@@ -0,0 +1,44 @@
+package file
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mattes/log"
+)
+
+func TestParseConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	cfg, err := log.ParseConfig([]byte(`
+outputs:
+  rotatingFile:
+    level: warn
+    path: ` + path + `
+    maxBytes: 1024
+    interval: 24h
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := cfg.Outputs[0].Config.(*Config)
+	if c.MaxBytes != 1024 || c.Interval.Hours() != 24 || !c.Compress {
+		t.Errorf("unexpected config %+v", c)
+	}
+
+	logger, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("dropped")
+	logger.Warn("hello")
+	logger.Sync()
+
+	body, _ := ioutil.ReadFile(path)
+	if strings.Contains(string(body), "dropped") || !strings.Contains(string(body), `"msg":"hello"`) {
+		t.Errorf("unexpected file content %q", body)
+	}
+}
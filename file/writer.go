@@ -0,0 +1,389 @@
+package file
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// Writer is a zapcore.WriteSyncer that writes to a file and rotates it.
+// Rotated files are compressed and deleted in the background.
+type Writer struct {
+	cfg Config
+	now func() time.Time // for tests
+
+	mu       sync.Mutex
+	f        *os.File
+	size     int64
+	openedAt time.Time
+	closed   bool
+	errs     error // from the background, returned by Sync
+
+	mill     chan struct{} // triggers compressing and deleting rotated files
+	millDone chan struct{}
+	sighup   chan os.Signal
+}
+
+// Open opens the file for writing, without building a core. Use it to write
+// to a rotating file with a custom encoder, i.e.
+//
+//	w, err := c.Open()
+//	core := zapcore.NewCore(encoder, w, c.Level)
+func (cfg Config) Open() (*Writer, error) {
+	return cfg.open(time.Now)
+}
+
+func (cfg Config) open(now func() time.Time) (*Writer, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("missing Path")
+	}
+	if cfg.MaxBytes < 0 {
+		return nil, fmt.Errorf("invalid MaxBytes")
+	}
+	if cfg.Interval < 0 {
+		return nil, fmt.Errorf("invalid Interval")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return nil, err
+	}
+
+	w := &Writer{
+		cfg:      cfg,
+		now:      now,
+		mill:     make(chan struct{}, 1),
+		millDone: make(chan struct{}),
+	}
+
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	go w.runMill()
+	w.triggerMill() // clean up files from previous runs
+
+	if cfg.ReopenOnSIGHUP {
+		w.sighup = make(chan os.Signal, 1)
+		signal.Notify(w.sighup, syscall.SIGHUP)
+		go w.handleSIGHUP()
+	}
+
+	return w, nil
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	now := w.now()
+	if w.shouldRotate(int64(len(p)), now) {
+		if err := w.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Sync commits the file to disk and returns errors
+// that happened in the background since the last Sync.
+func (w *Writer) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+
+	err := multierr.Append(w.f.Sync(), w.errs)
+	w.errs = nil
+	return err
+}
+
+// Rotate rotates the file now.
+func (w *Writer) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.rotate(w.now())
+}
+
+// Reopen closes and reopens the file, after it was moved by an external tool.
+func (w *Writer) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+// Close closes the file and waits for rotated files to be compressed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	if w.sighup != nil {
+		signal.Stop(w.sighup)
+		close(w.sighup)
+	}
+	err := multierr.Append(w.f.Close(), w.errs)
+	close(w.mill)
+	w.mu.Unlock()
+
+	<-w.millDone
+	return err
+}
+
+func (w *Writer) shouldRotate(n int64, now time.Time) bool {
+	if w.size == 0 {
+		// nothing to rotate, just start a new interval
+		w.openedAt = now
+		return false
+	}
+
+	if w.cfg.MaxBytes > 0 && w.size+n > w.cfg.MaxBytes {
+		return true
+	}
+
+	if w.cfg.Interval > 0 && !now.Truncate(w.cfg.Interval).Equal(w.openedAt.Truncate(w.cfg.Interval)) {
+		return true
+	}
+
+	return false
+}
+
+// open opens the file, w.mu must be held.
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	w.f = f
+	w.size = info.Size()
+	w.openedAt = w.now()
+	if w.size > 0 {
+		w.openedAt = info.ModTime() // rotate files of the last interval after restarts
+	}
+	return nil
+}
+
+// rotate moves the file aside and opens a new one, w.mu must be held.
+func (w *Writer) rotate(now time.Time) error {
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+
+	// find an unused name, in case of multiple rotations per millisecond
+	t := now
+	for {
+		name := w.backupName(t)
+		if !exists(name) && !exists(name+".gz") {
+			if err := os.Rename(w.cfg.Path, name); err != nil {
+				return err
+			}
+			break
+		}
+		t = t.Add(time.Millisecond)
+	}
+
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	w.triggerMill()
+	return nil
+}
+
+func (w *Writer) backupName(t time.Time) string {
+	prefix, ext := w.backupPrefix()
+	return prefix + t.UTC().Format(backupTimeFormat) + ext
+}
+
+func (w *Writer) backupPrefix() (prefix, ext string) {
+	ext = filepath.Ext(w.cfg.Path)
+	return strings.TrimSuffix(w.cfg.Path, ext) + "-", ext
+}
+
+// triggerMill doesn't block, w.mu must be held.
+func (w *Writer) triggerMill() {
+	select {
+	case w.mill <- struct{}{}:
+	default: // already triggered
+	}
+}
+
+func (w *Writer) runMill() {
+	defer close(w.millDone)
+
+	for range w.mill {
+		if err := w.runMillOnce(); err != nil {
+			w.mu.Lock()
+			w.errs = multierr.Append(w.errs, err)
+			w.mu.Unlock()
+		}
+	}
+}
+
+type backup struct {
+	path string
+	t    time.Time
+}
+
+// runMillOnce deletes rotated files according to MaxBackups and MaxAge
+// and compresses the remaining ones.
+func (w *Writer) runMillOnce() error {
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+
+	// newest first
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].t.After(backups[j].t)
+	})
+
+	var errs error
+	keep := backups[:0]
+	for i, b := range backups {
+		if (w.cfg.MaxBackups > 0 && i >= w.cfg.MaxBackups) ||
+			(w.cfg.MaxAge > 0 && w.now().Sub(b.t) > w.cfg.MaxAge) {
+			errs = multierr.Append(errs, os.Remove(b.path))
+			continue
+		}
+		keep = append(keep, b)
+	}
+
+	if w.cfg.Compress {
+		for _, b := range keep {
+			if !strings.HasSuffix(b.path, ".gz") {
+				errs = multierr.Append(errs, compress(b.path))
+			}
+		}
+	}
+
+	return errs
+}
+
+// backups returns the rotated files of w.
+func (w *Writer) backups() ([]backup, error) {
+	entries, err := os.ReadDir(filepath.Dir(w.cfg.Path))
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, ext := w.backupPrefix()
+	prefix = filepath.Base(prefix)
+
+	var backups []backup
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		ts := strings.TrimPrefix(strings.TrimSuffix(name, ".gz"), prefix)
+		if !strings.HasSuffix(ts, ext) {
+			continue
+		}
+
+		t, err := time.Parse(backupTimeFormat, strings.TrimSuffix(ts, ext))
+		if err != nil {
+			continue // not ours
+		}
+
+		backups = append(backups, backup{path: filepath.Join(filepath.Dir(w.cfg.Path), name), t: t})
+	}
+	return backups, nil
+}
+
+func (w *Writer) handleSIGHUP() {
+	for range w.sighup {
+		if err := w.Reopen(); err != nil {
+			w.mu.Lock()
+			w.errs = multierr.Append(w.errs, err)
+			w.mu.Unlock()
+		}
+	}
+}
+
+// compress gzips path into path.gz and removes path.
+func compress(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// write to a temporary file first, so there is never a partial .gz
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp)
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, path+".gz"); err != nil {
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,157 @@
+package file
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type clock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *clock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *clock) add(d time.Duration) {
+	c.mu.Lock()
+	c.t = c.t.Add(d)
+	c.mu.Unlock()
+}
+
+func files(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0)
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestWriterRotateSize(t *testing.T) {
+	dir := t.TempDir()
+	c := &clock{t: time.Date(2021, 8, 1, 10, 0, 0, 0, time.UTC)}
+
+	cfg := NewConfig()
+	cfg.Path = filepath.Join(dir, "app.log")
+	cfg.MaxBytes = 10
+	cfg.MaxBackups = 2
+
+	w, err := cfg.open(c.now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n", "dddddd\n"} {
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+		c.add(time.Second)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []string{
+		"app-2021-08-01T10-00-02.000.log.gz", // oldest backup "aaaaaa" was deleted
+		"app-2021-08-01T10-00-03.000.log.gz",
+		"app.log",
+	}
+	if names := files(t, dir); strings.Join(names, ",") != strings.Join(expect, ",") {
+		t.Fatalf("expected %v, got %v", expect, names)
+	}
+
+	f, err := os.Open(filepath.Join(dir, expect[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(gz)
+	if string(body) != "cccccc\n" {
+		t.Errorf("unexpected compressed content %q", body)
+	}
+}
+
+func TestWriterRotateInterval(t *testing.T) {
+	dir := t.TempDir()
+	c := &clock{t: time.Date(2021, 8, 1, 23, 59, 0, 0, time.UTC)}
+
+	cfg := NewConfig()
+	cfg.Path = filepath.Join(dir, "app.log")
+	cfg.Interval = 24 * time.Hour
+	cfg.Compress = false
+	cfg.MaxAge = 48 * time.Hour
+
+	w, err := cfg.open(c.now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w.Write([]byte("day 1\n"))
+	c.add(time.Minute)
+	w.Write([]byte("day 2\n"))
+	c.add(time.Minute)
+	w.Write([]byte("day 2\n"))
+	w.Close()
+
+	expect := []string{"app-2021-08-02T00-00-00.000.log", "app.log"}
+	if names := files(t, dir); strings.Join(names, ",") != strings.Join(expect, ",") {
+		t.Fatalf("expected %v, got %v", expect, names)
+	}
+
+	// max age is applied on open
+	c.add(72 * time.Hour)
+	w, err = cfg.open(c.now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if names := files(t, dir); strings.Join(names, ",") != "app.log" {
+		t.Errorf("expected old backup to be deleted, got %v", names)
+	}
+}
+
+func TestWriterReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	cfg := NewConfig()
+	cfg.Path = path
+	w, err := cfg.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	w.Write([]byte("before\n"))
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("after\n"))
+
+	body, _ := ioutil.ReadFile(path)
+	if string(body) != "after\n" {
+		t.Errorf("expected new file, got %q", body)
+	}
+}
@@ -0,0 +1,172 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type FlightRecorderConfig struct {
+	// Size is the number of entries kept in memory.
+	Size int
+
+	// TriggerLevel dumps the recorded entries, if an entry at or above this
+	// level is logged. Panic and fatal entries always trigger a dump,
+	// since the program is about to crash.
+	TriggerLevel zapcore.LevelEnabler
+}
+
+func NewFlightRecorderConfig() FlightRecorderConfig {
+	return FlightRecorderConfig{
+		Size:         1000,
+		TriggerLevel: zap.NewAtomicLevelAt(zap.ErrorLevel),
+	}
+}
+
+// Build wraps core with a FlightRecorder.
+func (cfg FlightRecorderConfig) Build(core zapcore.Core) (*FlightRecorder, error) {
+	if cfg.Size <= 0 {
+		return nil, fmt.Errorf("invalid Size")
+	}
+	if cfg.TriggerLevel == nil {
+		return nil, fmt.Errorf("missing TriggerLevel")
+	}
+
+	return &FlightRecorder{
+		Core:    core,
+		trigger: cfg.TriggerLevel,
+		buf:     &flightBuffer{records: make([]flightRecord, cfg.Size)},
+	}, nil
+}
+
+// FlightRecorder is a core that keeps the last entries which the wrapped
+// core doesn't write, i.e. because of its level, in a ring buffer. Once an entry
+// at trigger level is logged, i.e. an error, the recorded entries are written
+// to the wrapped core first, to give context about what led up to it.
+//
+// The wrapped core must be enabled for the trigger level. Since every entry
+// at every level is recorded, debug logs aren't free anymore.
+//
+//	c := log.NewFlightRecorderConfig()
+//	logger := zap.New(core, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+//		fr, _ := c.Build(core)
+//		return fr
+//	}))
+type FlightRecorder struct {
+	zapcore.Core
+
+	trigger zapcore.LevelEnabler
+	buf     *flightBuffer // shared with clones
+}
+
+type flightRecord struct {
+	core   zapcore.Core // the wrapped core with its context fields
+	entry  zapcore.Entry
+	fields []zapcore.Field
+}
+
+type flightBuffer struct {
+	mu      sync.Mutex
+	records []flightRecord
+	next    int // index of the next record
+	full    bool
+}
+
+func (fr *FlightRecorder) Enabled(zapcore.Level) bool {
+	return true // record everything
+}
+
+func (fr *FlightRecorder) With(fields []zapcore.Field) zapcore.Core {
+	return &FlightRecorder{
+		Core:    fr.Core.With(fields),
+		trigger: fr.trigger,
+		buf:     fr.buf,
+	}
+}
+
+func (fr *FlightRecorder) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	// ask the core, not its level, which can depend on the logger name
+	ce := fr.Core.Check(entry, nil)
+	if ce == nil {
+		return checkedEntry.AddCore(entry, flightRecording{fr}) // record it
+	}
+	return checkedEntry.AddCore(entry, &checkedCore{Core: fr.Core, ce: ce, write: fr.write})
+}
+
+// Write records entry, if the wrapped core doesn't write it,
+// or writes it after dumping the recorded entries, if it triggers.
+func (fr *FlightRecorder) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	if fr.Core.Check(entry, nil) == nil {
+		return fr.record(entry, fields)
+	}
+	return fr.write(entry, fields, fr.Core.Write)
+}
+
+func (fr *FlightRecorder) write(entry zapcore.Entry, fields []zapcore.Field, next writeFunc) error {
+	var err error
+	if fr.triggers(entry.Level) {
+		err = fr.Dump() // before the entry is written
+	}
+	return multierr.Append(err, next(entry, fields))
+}
+
+func (fr *FlightRecorder) record(entry zapcore.Entry, fields []zapcore.Field) error {
+	// fields might be reused by the caller
+	r := flightRecord{
+		core:   fr.Core,
+		entry:  entry,
+		fields: append([]zapcore.Field(nil), fields...),
+	}
+
+	fr.buf.mu.Lock()
+	fr.buf.records[fr.buf.next] = r
+	fr.buf.next = (fr.buf.next + 1) % len(fr.buf.records)
+	if fr.buf.next == 0 {
+		fr.buf.full = true
+	}
+	fr.buf.mu.Unlock()
+
+	return nil
+}
+
+// flightRecording records the entries checked by a FlightRecorder,
+// that the wrapped core doesn't write.
+type flightRecording struct {
+	*FlightRecorder
+}
+
+func (r flightRecording) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return r.record(entry, fields)
+}
+
+// Dump writes all recorded entries to the wrapped core, regardless of
+// its level, and clears the buffer.
+func (fr *FlightRecorder) Dump() error {
+	fr.buf.mu.Lock()
+	records := make([]flightRecord, 0, len(fr.buf.records))
+	if fr.buf.full {
+		records = append(records, fr.buf.records[fr.buf.next:]...)
+	}
+	records = append(records, fr.buf.records[:fr.buf.next]...)
+
+	// reset buffer
+	for i := range fr.buf.records {
+		fr.buf.records[i] = flightRecord{}
+	}
+	fr.buf.next = 0
+	fr.buf.full = false
+	fr.buf.mu.Unlock()
+
+	var err error
+	for _, r := range records {
+		err = multierr.Append(err, r.core.Write(r.entry, r.fields))
+	}
+	return err
+}
+
+func (fr *FlightRecorder) triggers(level zapcore.Level) bool {
+	return level >= zapcore.PanicLevel || fr.trigger.Enabled(level)
+}
@@ -0,0 +1,93 @@
+package log
+
+import (
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestFlightRecorder(t *testing.T) {
+	core, obs := observer.New(zapcore.InfoLevel)
+
+	c := NewFlightRecorderConfig()
+	c.Size = 2
+	fr, err := c.Build(core)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.New(fr).With(zap.String("request", "1"))
+
+	logger.Debug("debug 1") // overwritten
+	logger.Debug("debug 2")
+	logger.Info("info")
+	logger.Debug("debug 3")
+
+	if msgs := messages(obs); !reflect.DeepEqual(msgs, []string{"info"}) {
+		t.Fatalf("expected only info, got %v", msgs)
+	}
+
+	logger.Error("error")
+	logs := obs.TakeAll()
+	msgs := make([]string, 0)
+	for _, l := range logs {
+		msgs = append(msgs, l.Message)
+		if l.ContextMap()["request"] != "1" {
+			t.Errorf("%q: missing context field", l.Message)
+		}
+	}
+	if expect := []string{"debug 2", "debug 3", "error"}; !reflect.DeepEqual(msgs, expect) {
+		t.Errorf("expected %v, got %v", expect, msgs)
+	}
+
+	// buffer was cleared
+	logger.Error("error")
+	if msgs := messages(obs); !reflect.DeepEqual(msgs, []string{"error"}) {
+		t.Errorf("expected cleared buffer, got %v", msgs)
+	}
+}
+
+func TestFlightRecorderPanic(t *testing.T) {
+	core, obs := observer.New(zapcore.InfoLevel)
+
+	c := NewFlightRecorderConfig()
+	c.TriggerLevel = zapcore.FatalLevel
+	fr, err := c.Build(core)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.New(fr)
+
+	logger.Debug("debug")
+	logger.Error("error") // no trigger
+	func() {
+		defer func() { recover() }()
+		logger.Panic("panic")
+	}()
+
+	if msgs, expect := messages(obs), []string{"error", "debug", "panic"}; !reflect.DeepEqual(msgs, expect) {
+		t.Errorf("expected %v, got %v", expect, msgs)
+	}
+}
+
+func TestFlightRecorderNamedLevels(t *testing.T) {
+	setTestLevels(t, zapcore.InfoLevel)
+	SetLevel("db", zapcore.DebugLevel)
+
+	core, obs := observer.New(zapcore.DebugLevel)
+	fr, err := NewFlightRecorderConfig().Build(&namedLevelsCore{Core: core, r: levels})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.New(fr)
+
+	logger.Named("db").Debug("query")
+	logger.Named("http").Debug("request") // recorded
+	logger.Named("http").Error("failed")
+
+	if msgs, expect := messages(obs), []string{"query", "request", "failed"}; !reflect.DeepEqual(msgs, expect) {
+		t.Errorf("expected %v, got %v", expect, msgs)
+	}
+}
@@ -8,6 +8,8 @@ import (
 
 	"cloud.google.com/go/errorreporting"
 	"github.com/mattes/errorstats"
+	"github.com/mattes/log"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/api/option"
@@ -32,21 +34,29 @@ func Request(r *http.Request) zapcore.Field {
 type Config struct {
 	// Level is the minimum enabled logging level.
 	// By default, only >= errors are send to Slack.
-	Level zap.AtomicLevel
+	Level zap.AtomicLevel `yaml:"level"`
 
 	// Project sets the Google Cloud Project. If empty the GCE metadata server is asked for it.
-	Project string
+	Project string `yaml:"project"`
 
 	// ServiceName identifies the running program and is included in the error reports.
-	ServiceName string
+	ServiceName string `yaml:"serviceName"`
 
 	// ServiceVersion identifies the version of the running program and is
 	// included in the error reports.
-	ServiceVersion string
+	ServiceVersion string `yaml:"serviceVersion"`
 
 	// Options for error reporting client, i.e.
 	// option.WithCredentialsFile("credentials.json")
-	ClientOptions []option.ClientOption
+	ClientOptions []option.ClientOption `yaml:"-"`
+}
+
+// register as "googleErrorReporting" output for log.LoadConfig
+func init() {
+	log.RegisterOutput("googleErrorReporting", func() log.CoreBuilder {
+		c := NewConfig()
+		return &c
+	})
 }
 
 func NewConfig() Config {
@@ -141,8 +151,9 @@ func (c *core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
 		}
 	}
 
-	// marshal fields into json for human output
-	buf, err := c.fieldsEnc.EncodeEntry(zapcore.Entry{}, fields)
+	// marshal fields into json for human output,
+	// without error stacks, which are reported as r.Stack
+	buf, err := c.fieldsEnc.EncodeEntry(zapcore.Entry{}, log.OmitErrorStacks(fields))
 	if err != nil {
 		return err
 	}
@@ -162,13 +173,18 @@ func (c *core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
 
 	r.Error = errors.New(errorStr)
 
-	// Add stacktrace.
-	// Ignore entry.Stack which will be empty anyway.
-	// Also, it appears that entry.Stack doesn't conform with
-	// https://cloud.google.com/error-reporting/reference/rest/v1beta1/projects.events/report#ReportedErrorEvent.message
-	// Limit the stack trace to 16k.
-	var sbuf [16 * 1024]byte
-	r.Stack = trimStack(sbuf[0:runtime.Stack(sbuf[:], false)])
+	// Add stacktrace. Prefer the stack of a logged error, which shows
+	// where the error happened, over the stack of the log call.
+	if frames := log.ErrorStack(fields); len(frames) > 0 {
+		r.Stack = []byte(log.FormatStack(frames))
+	} else {
+		// Ignore entry.Stack which will be empty anyway.
+		// Also, it appears that entry.Stack doesn't conform with
+		// https://cloud.google.com/error-reporting/reference/rest/v1beta1/projects.events/report#ReportedErrorEvent.message
+		// Limit the stack trace to 16k.
+		var sbuf [16 * 1024]byte
+		r.Stack = trimStack(sbuf[0:runtime.Stack(sbuf[:], false)])
+	}
 
 	// send error report
 	c.client.Report(r)
@@ -181,8 +197,9 @@ func (c *core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
 	}
 
 	// see if any errors have been logged in the meanwhile,
-	// if yes, return them here.
-	return c.errs.ErrAndReset()
+	// if yes, return them here. They belong to earlier entries,
+	// this one was accepted, see log.Failover.
+	return log.DeferredError(c.errs.ErrAndReset())
 }
 
 func (c *core) Sync() error {
@@ -190,6 +207,14 @@ func (c *core) Sync() error {
 	return c.errs.ErrAndReset()
 }
 
+// Close flushes and closes the client, the core can't be used afterwards.
+// It's called by log.Shutdown.
+func (c *core) Close() error {
+	return multierr.Combine(
+		c.client.Close(),
+		c.errs.ErrAndReset())
+}
+
 func (c *core) clone() *core {
 	return &core{
 		LevelEnabler: c.LevelEnabler,
@@ -9,6 +9,7 @@ import (
 
 	"cloud.google.com/go/logging"
 	"github.com/mattes/errorstats"
+	"github.com/mattes/log"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -95,7 +96,7 @@ func SpanID(id string) zapcore.Field {
 type Config struct {
 	// Level is the minimum enabled logging level.
 	// By default, only >= info levels are logged.
-	Level zap.AtomicLevel
+	Level zap.AtomicLevel `yaml:"level"`
 
 	// LogName can be either a project id or:
 	// projects/PROJECT_ID
@@ -104,7 +105,7 @@ type Config struct {
 	// organizations/ORG_ID
 	//
 	// If empty, project id will be used from metadata server.
-	LogName string
+	LogName string `yaml:"logName"`
 
 	// LogID must be less than 512 characters long and can only
 	// include the following characters: upper and lower case alphanumeric
@@ -112,53 +113,61 @@ type Config struct {
 	// underscore, hyphen, and period.
 	//
 	// Example LogIDs: https://cloud.google.com/logging/docs/agent/default-logs#custom_types
-	LogID string
+	LogID string `yaml:"logID"`
 
 	// BufferedByteLimit is the maximum number of bytes that the Logger will keep
 	// in memory before returning ErrOverflow.
-	BufferedByteLimit int
+	BufferedByteLimit int `yaml:"bufferedByteLimit"`
 
 	// ConcurrentWriteLimit determines how many goroutines will send log entries
 	// to the underlying service. Set ConcurrentWriteLimit to a higher value to
 	// increase throughput.
-	ConcurrentWriteLimit int
+	ConcurrentWriteLimit int `yaml:"concurrentWriteLimit"`
 
 	// DelayThreshold is the maximum amount of time that an entry should remain
 	// buffered in memory before a call to the logging service is triggered.
 	// Larger values of DelayThreshold will generally result in fewer calls to
 	// the logging service, while increasing the risk that log entries will be
 	// lost if the process crashes.
-	DelayThreshold time.Duration
+	DelayThreshold time.Duration `yaml:"delayThreshold"`
 
 	// EntryByteLimit is the maximum number of bytes of entries that will be sent
 	// in a single call to the logging service. If EntryByteLimit is smaller than
 	// EntryByteThreshold, the latter has no effect. The default is zero, meaning
 	// there is no limit.
-	EntryByteLimit int
+	EntryByteLimit int `yaml:"entryByteLimit"`
 
 	// EntryByteThreshold is the maximum number of bytes of entries that will be
 	// buffered in memory before a call to the logging service is triggered.
 	// See EntryCountThreshold for a discussion of the tradeoffs involved in
 	// setting this option.
-	EntryByteThreshold int
+	EntryByteThreshold int `yaml:"entryByteThreshold"`
 
 	// EntryCountThreshold is the maximum number of entries that will be buffered
 	// in memory before a call to the logging service is triggered. Larger values
 	// will generally result in fewer calls to the logging service, while increasing
 	// both memory consumption and the risk that log entries will be lost if the
 	// process crashes.
-	EntryCountThreshold int
+	EntryCountThreshold int `yaml:"entryCountThreshold"`
 
 	// MonitoredResource sets the monitored resource associated with all log entries
 	// written from a Logger. If not provided, the resource is automatically detected
 	// based on the running environment (on GCE and GAE Standard only).
 	// It translates to https://godoc.org/google.golang.org/genproto/googleapis/api/monitoredres#MonitoredResource
-	MonitoredResourceType   string
-	MonitoredResourceLabels map[string]string
+	MonitoredResourceType   string            `yaml:"monitoredResourceType"`
+	MonitoredResourceLabels map[string]string `yaml:"monitoredResourceLabels"`
 
 	// Options for logging client, i.e.
 	// option.WithCredentialsFile("credentials.json")
-	ClientOptions []option.ClientOption
+	ClientOptions []option.ClientOption `yaml:"-"`
+}
+
+// register as "googleStackdriver" output for log.LoadConfig
+func init() {
+	log.RegisterOutput("googleStackdriver", func() log.CoreBuilder {
+		c := NewConfig()
+		return &c
+	})
 }
 
 func NewConfig() Config {
@@ -290,6 +299,86 @@ func (c *core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
 		e.Severity = logging.Default
 	}
 
+	setCustomFields(&e, fields)
+
+	// marshal fields into json for human output
+	// TODO should we use e.Labels instead?
+	// without error stacks, which are appended below
+	buf, err := c.fieldsEnc.EncodeEntry(zapcore.Entry{}, log.OmitErrorStacks(fields))
+	if err != nil {
+		return err
+	}
+	fieldsStr := buf.String()
+
+	errorStr := entry.Message
+	if len(fieldsStr) > 0 {
+		errorStr += " " + fieldsStr
+	}
+
+	// add the stack of a logged error, which Error Reporting picks up
+	if frames := log.ErrorStack(fields); len(frames) > 0 {
+		errorStr += "\n\n" + log.FormatStack(frames)
+	}
+
+	e.Payload = errorStr
+
+	if entry.Caller.Defined {
+		if entry.Caller.Function == "" {
+			entry.Caller.Function = funcNameForPC(entry.Caller.PC)
+		}
+		e.SourceLocation = &logpb.LogEntrySourceLocation{
+			File:     entry.Caller.File,
+			Line:     int64(entry.Caller.Line),
+			Function: entry.Caller.Function,
+		}
+	}
+
+	if entry.LoggerName != "" {
+		e.Labels["logger"] = entry.LoggerName
+	}
+
+	// log message
+	c.logger.Log(e)
+
+	if entry.Level > zapcore.ErrorLevel {
+		// Since we may be crashing the program, sync the output
+		// errors, pending a clean solution to issue #370.
+		// https://github.com/uber-go/zap/issues/370
+		c.Sync()
+	}
+
+	// see if any errors have been logged in the meanwhile,
+	// if yes, return them here. They belong to earlier entries,
+	// this one was accepted, see log.Failover.
+	return log.DeferredError(c.errs.ErrAndReset())
+}
+
+func (c *core) Sync() error {
+	return multierr.Combine(
+		c.logger.Flush(),
+		c.errs.ErrAndReset())
+}
+
+// Close flushes and closes the client, the core can't be used afterwards.
+// It's called by log.Shutdown.
+func (c *core) Close() error {
+	return multierr.Combine(
+		c.client.Close(),
+		c.errs.ErrAndReset())
+}
+
+func (c *core) clone() *core {
+	return &core{
+		LevelEnabler: c.LevelEnabler,
+		client:       c.client,
+		logger:       c.logger,
+		fieldsEnc:    c.fieldsEnc.Clone(),
+		errs:         c.errs,
+	}
+}
+
+// setCustomFields sets the fields of e from custom fields, like Request.
+func setCustomFields(e *logging.Entry, fields []zapcore.Field) {
 	e.HTTPRequest = &logging.HTTPRequest{Request: &http.Request{URL: &url.URL{}}}
 	httpRequestSet := false
 
@@ -346,63 +435,6 @@ func (c *core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
 	if !httpRequestSet {
 		e.HTTPRequest = nil
 	}
-
-	// marshal fields into json for human output
-	// TODO should we use e.Labels instead?
-	buf, err := c.fieldsEnc.EncodeEntry(zapcore.Entry{}, fields)
-	if err != nil {
-		return err
-	}
-	fieldsStr := buf.String()
-
-	errorStr := entry.Message
-	if len(fieldsStr) > 0 {
-		errorStr += " " + fieldsStr
-	}
-
-	e.Payload = errorStr
-
-	if entry.Caller.Defined {
-		e.SourceLocation = &logpb.LogEntrySourceLocation{
-			File:     entry.Caller.File,
-			Line:     int64(entry.Caller.Line),
-			Function: funcNameForPC(entry.Caller.PC),
-		}
-	}
-
-	if entry.LoggerName != "" {
-		e.Labels["logger"] = entry.LoggerName
-	}
-
-	// log message
-	c.logger.Log(e)
-
-	if entry.Level > zapcore.ErrorLevel {
-		// Since we may be crashing the program, sync the output
-		// errors, pending a clean solution to issue #370.
-		// https://github.com/uber-go/zap/issues/370
-		c.Sync()
-	}
-
-	// see if any errors have been logged in the meanwhile,
-	// if yes, return them here.
-	return c.errs.ErrAndReset()
-}
-
-func (c *core) Sync() error {
-	return multierr.Combine(
-		c.logger.Flush(),
-		c.errs.ErrAndReset())
-}
-
-func (c *core) clone() *core {
-	return &core{
-		LevelEnabler: c.LevelEnabler,
-		client:       c.client,
-		logger:       c.logger,
-		fieldsEnc:    c.fieldsEnc.Clone(),
-		errs:         c.errs,
-	}
 }
 
 func funcNameForPC(pc uintptr) string {
@@ -0,0 +1,76 @@
+package googleStackdriver
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging"
+	"github.com/mattes/log"
+	"github.com/mattes/log/spool"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fieldsCore sends the fields of written entries to a channel.
+type fieldsCore struct {
+	zapcore.LevelEnabler
+	fields chan []zapcore.Field
+}
+
+func (c *fieldsCore) With([]zapcore.Field) zapcore.Core { return c }
+func (c *fieldsCore) Sync() error                       { return nil }
+
+func (c *fieldsCore) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checkedEntry.AddCore(entry, c)
+}
+
+func (c *fieldsCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	c.fields <- fields
+	return nil
+}
+
+func TestSpooledFields(t *testing.T) {
+	core := &fieldsCore{LevelEnabler: zapcore.InfoLevel, fields: make(chan []zapcore.Field, 1)}
+
+	c := spool.NewConfig()
+	c.Dir = t.TempDir()
+	s, err := c.Build(core)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	err = fmt.Errorf("charge failed: %w", errors.New("card declined"))
+	zap.New(s).Error("failed",
+		Request(httptest.NewRequest("POST", "https://example.com/charge", nil)),
+		RequestLatency(time.Second),
+		RequestStatus(402),
+		Trace("projects/p/traces/abc"),
+		log.Err(err),
+	)
+
+	var fields []zapcore.Field
+	select {
+	case fields = <-core.fields:
+	case <-time.After(time.Second):
+		t.Fatal("entry wasn't shipped")
+	}
+
+	var e logging.Entry
+	setCustomFields(&e, fields)
+	if e.HTTPRequest == nil || e.HTTPRequest.Request.Method != "POST" || e.HTTPRequest.Request.URL.Path != "/charge" ||
+		e.HTTPRequest.Latency != time.Second || e.HTTPRequest.Status != 402 {
+		t.Errorf("unexpected http request %+v", e.HTTPRequest)
+	}
+	if e.Trace != "projects/p/traces/abc" {
+		t.Errorf("unexpected trace %q", e.Trace)
+	}
+
+	replayed, ok := log.FieldError(fields[len(fields)-1])
+	if !ok || replayed.Error() != err.Error() || errors.Unwrap(replayed).Error() != "card declined" {
+		t.Errorf("unexpected error field %v", replayed)
+	}
+}
@@ -7,7 +7,7 @@ require (
 	cloud.google.com/go/logging v1.4.2
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
 	github.com/mattes/errorstats v0.0.0-20191110073129-45b03e061d62
-	go.uber.org/atomic v1.9.0 // indirect
+	github.com/mattes/log v0.0.0-20210214020244-7a8213947092
 	go.uber.org/multierr v1.7.0
 	go.uber.org/zap v1.18.1
 	golang.org/x/net v0.0.0-20210726213435-c6fcb2dbf985 // indirect
@@ -15,3 +15,5 @@ require (
 	google.golang.org/genproto v0.0.0-20210728212813-7823e685a01f
 	google.golang.org/grpc v1.39.0
 )
+
+replace github.com/mattes/log => ../
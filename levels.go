@@ -0,0 +1,213 @@
+package log
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// levels is the level registry used by NamedLevels
+var levels = newLevelRegistry(zapcore.DebugLevel)
+
+// maxLoggerNames is the number of logger names remembered for LoggerNames
+const maxLoggerNames = 4096
+
+// SetLevel sets the minimum enabled level for loggers with the given name, see Named.
+// Names ending in ".*" match the logger and all its children, i.e. "db.*"
+// matches "db" and "db.pool". The empty name sets the global level.
+// The most specific name wins.
+//
+// Levels only apply to loggers with the NamedLevels option, like the default logger.
+func SetLevel(name string, level zapcore.Level) {
+	levels.set(name, level)
+}
+
+// UnsetLevel removes a level set with SetLevel.
+// The global level can't be unset.
+func UnsetLevel(name string) {
+	levels.unset(name)
+}
+
+// Level returns the effective level for a logger name.
+func Level(name string) zapcore.Level {
+	return levels.level(name)
+}
+
+// Levels returns all levels set with SetLevel, including the global level.
+func Levels() map[string]zapcore.Level {
+	return levels.all()
+}
+
+// LoggerNames returns the sorted names of all loggers that logged through
+// a logger with the NamedLevels option. The unnamed logger is not included.
+// At most maxLoggerNames names are remembered, so that loggers named after
+// request data can't grow memory without bound. Later names are not included.
+func LoggerNames() []string {
+	return levels.names()
+}
+
+// NamedLevels returns a zap.Option that wraps a core, so that logs below the
+// levels set with SetLevel are dropped. The core's own level still applies,
+// so it should be set to the lowest level that's ever needed.
+func NamedLevels() zap.Option {
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &namedLevelsCore{Core: core, r: levels}
+	})
+}
+
+type levelRegistry struct {
+	nseen   int64        // names in seen, first for 64-bit alignment
+	mu      sync.Mutex   // serializes writes to current
+	current atomic.Value // *levelSnapshot, copy on write
+	seen    sync.Map     // logger names, at most maxLoggerNames
+}
+
+// levelSnapshot is never modified after it's stored
+type levelSnapshot struct {
+	levels map[string]zapcore.Level
+	min    zapcore.Level
+}
+
+func newLevelRegistry(global zapcore.Level) *levelRegistry {
+	r := &levelRegistry{}
+	r.current.Store(&levelSnapshot{
+		levels: map[string]zapcore.Level{"": global},
+		min:    global,
+	})
+	return r
+}
+
+func (r *levelRegistry) snapshot() *levelSnapshot {
+	return r.current.Load().(*levelSnapshot)
+}
+
+func (r *levelRegistry) set(name string, level zapcore.Level) {
+	r.update(func(levels map[string]zapcore.Level) {
+		levels[normalizeLevelName(name)] = level
+	})
+}
+
+func (r *levelRegistry) unset(name string) {
+	name = normalizeLevelName(name)
+	if name == "" {
+		return
+	}
+
+	r.update(func(levels map[string]zapcore.Level) {
+		delete(levels, name)
+	})
+}
+
+func (r *levelRegistry) update(fn func(levels map[string]zapcore.Level)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	levels := r.all()
+	fn(levels)
+
+	min := zapcore.FatalLevel
+	for _, l := range levels {
+		if l < min {
+			min = l
+		}
+	}
+
+	r.current.Store(&levelSnapshot{levels: levels, min: min})
+}
+
+// level returns the level of the most specific match for name
+func (r *levelRegistry) level(name string) zapcore.Level {
+	levels := r.snapshot().levels
+
+	if name != "" {
+		if l, ok := levels[name]; ok {
+			return l
+		}
+
+		// walk up the hierarchy, i.e. a.b.c.*, a.b.*, a.*
+		for n := name; n != ""; {
+			if l, ok := levels[n+".*"]; ok {
+				return l
+			}
+			i := strings.LastIndexByte(n, '.')
+			if i < 0 {
+				break
+			}
+			n = n[:i]
+		}
+	}
+
+	return levels[""]
+}
+
+// all returns a copy of all levels
+func (r *levelRegistry) all() map[string]zapcore.Level {
+	levels := r.snapshot().levels
+	c := make(map[string]zapcore.Level, len(levels))
+	for k, v := range levels {
+		c[k] = v
+	}
+	return c
+}
+
+func (r *levelRegistry) remember(name string) {
+	if name == "" {
+		return
+	}
+	if _, ok := r.seen.Load(name); ok {
+		return
+	}
+	if atomic.AddInt64(&r.nseen, 1) > maxLoggerNames {
+		atomic.AddInt64(&r.nseen, -1)
+		return
+	}
+	if _, loaded := r.seen.LoadOrStore(name, struct{}{}); loaded {
+		atomic.AddInt64(&r.nseen, -1)
+	}
+}
+
+func (r *levelRegistry) names() []string {
+	names := make([]string, 0)
+	r.seen.Range(func(k, _ interface{}) bool {
+		names = append(names, k.(string))
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+// normalizeLevelName treats "*" as global level
+func normalizeLevelName(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "*" {
+		return ""
+	}
+	return name
+}
+
+type namedLevelsCore struct {
+	zapcore.Core
+	r *levelRegistry
+}
+
+func (c *namedLevelsCore) Enabled(level zapcore.Level) bool {
+	// some named logger might be enabled at this level
+	return level >= c.r.snapshot().min && c.Core.Enabled(level)
+}
+
+func (c *namedLevelsCore) With(fields []zapcore.Field) zapcore.Core {
+	return &namedLevelsCore{Core: c.Core.With(fields), r: c.r}
+}
+
+func (c *namedLevelsCore) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	c.r.remember(entry.LoggerName)
+
+	if entry.Level < c.r.level(entry.LoggerName) {
+		return checkedEntry
+	}
+	return c.Core.Check(entry, checkedEntry)
+}
@@ -0,0 +1,100 @@
+package log
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// setTestLevels resets the level registry until the test finishes
+func setTestLevels(t *testing.T, global zapcore.Level) {
+	prev := levels
+	levels = newLevelRegistry(global)
+	t.Cleanup(func() { levels = prev })
+}
+
+func TestNamedLevels(t *testing.T) {
+	setTestLevels(t, zapcore.InfoLevel)
+
+	core, obs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(core, NamedLevels())
+
+	SetLevel("db.*", zapcore.DebugLevel)
+	SetLevel("db.pool.conn", zapcore.ErrorLevel)
+
+	logger.Debug("root")                              // dropped
+	logger.Named("http").Debug("http")                // dropped
+	logger.Named("db").Debug("db")                    // logged
+	logger.Named("db").Named("pool").Debug("db.pool") // logged
+	logger.Named("db.pool.conn").Warn("db.pool.conn") // dropped
+	logger.Named("database").Debug("database")        // dropped
+	logger.Named("http").Info("http")                 // logged
+
+	expect := []string{"db", "db.pool", "http"}
+	if msgs := messages(obs); !reflect.DeepEqual(msgs, expect) {
+		t.Errorf("expected %v, got %v", expect, msgs)
+	}
+
+	// change at runtime
+	UnsetLevel("db.*")
+	SetLevel("", zapcore.DebugLevel)
+	logger.Named("http").Debug("http")
+	if msgs := messages(obs); !reflect.DeepEqual(msgs, []string{"http"}) {
+		t.Errorf("expected http log after changing level, got %v", msgs)
+	}
+
+	if names := LoggerNames(); !reflect.DeepEqual(names, []string{"database", "db", "db.pool", "db.pool.conn", "http"}) {
+		t.Errorf("unexpected logger names %v", names)
+	}
+}
+
+func TestLoggerNamesCap(t *testing.T) {
+	setTestLevels(t, zapcore.InfoLevel)
+
+	core, _ := observer.New(zapcore.DebugLevel)
+	logger := zap.New(core, NamedLevels())
+	for i := 0; i < maxLoggerNames+10; i++ {
+		logger.Named(fmt.Sprintf("user%d", i)).Info("hello")
+	}
+	logger.Named("user0").Info("hello")
+
+	if n := len(LoggerNames()); n != maxLoggerNames {
+		t.Errorf("expected %v names, got %v", maxLoggerNames, n)
+	}
+}
+
+func TestLevel(t *testing.T) {
+	setTestLevels(t, zapcore.WarnLevel)
+
+	SetLevel("a.*", zapcore.InfoLevel)
+	SetLevel("a.b", zapcore.ErrorLevel)
+	SetLevel("a.b.*", zapcore.DebugLevel)
+
+	tt := map[string]zapcore.Level{
+		"":      zapcore.WarnLevel,
+		"b":     zapcore.WarnLevel,
+		"a":     zapcore.InfoLevel,
+		"a.c":   zapcore.InfoLevel,
+		"a.b":   zapcore.ErrorLevel,
+		"a.b.c": zapcore.DebugLevel,
+		"ab":    zapcore.WarnLevel,
+	}
+
+	for name, expect := range tt {
+		if l := Level(name); l != expect {
+			t.Errorf("%q: expected %v, got %v", name, expect, l)
+		}
+	}
+}
+
+func messages(obs *observer.ObservedLogs) []string {
+	msgs := make([]string, 0)
+	for _, l := range obs.TakeAll() {
+		msgs = append(msgs, l.Message)
+	}
+	return msgs
+}
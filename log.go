@@ -1,43 +1,91 @@
 package log
 
 import (
+	"sync"
+	"sync/atomic"
+
 	"go.uber.org/zap"
 )
 
 var (
-	defaultLogger      *zap.Logger
-	defaultSugarLogger *zap.SugaredLogger
+	// defaults holds *loggers, it is swapped atomically so
+	// logging and replacing the default logger can happen concurrently.
+	defaults   atomic.Value
+	defaultsMu sync.Mutex // serializes Replace
 )
 
+// loggers keeps the default logger and its sugared version in sync.
+type loggers struct {
+	logger *zap.Logger
+	sugar  *zap.SugaredLogger
+}
+
 func init() {
 	setDefaultLogger()
 }
 
 func setDefaultLogger() {
-	logger, err := NewDevelopmentConfig().Build()
+	// honor environment variables, but never fail to start because of them
+	logger, envErr := newDefaultLogger(NewEnvConfig())
+	if envErr != nil {
+		var err error
+		logger, err = newDefaultLogger(NewDevelopmentConfig(), nil)
+		if err != nil {
+			panic(err) // this should not happen, if it does, we need to fix it
+		}
+	}
+
+	Use(logger)
+
+	if envErr != nil {
+		logger.WithOptions(zap.WithCaller(false)).
+			Warn("Ignoring invalid logging environment", zap.Error(envErr))
+	}
+}
+
+// newDefaultLogger builds cfg with the options of the default logger.
+func newDefaultLogger(cfg zap.Config, err error) (*zap.Logger, error) {
 	if err != nil {
-		panic(err) // this should not happen, if it does, we need to fix it
+		return nil, err
 	}
 
-	logger = logger.WithOptions(
+	// the level is enforced by NamedLevels, so it can be set per logger name
+	SetLevel("", cfg.Level.Level())
+	cfg.Level = zap.NewAtomicLevelAt(zap.DebugLevel)
+
+	return cfg.Build(
+		NamedLevels(),
 		zap.AddCaller(),
 		zap.AddCallerSkip(1),
 	)
-
-	Use(logger)
 }
 
 // Use sets the default logger used by the package
 func Use(logger *zap.Logger) {
-	defaultLogger = logger
-	defaultSugarLogger = logger.Sugar()
+	Replace(logger)
 }
 
-// CapturePanic captures, logs and re-throws a panic.
-// Only useful when used with defer.
-func CapturePanic() {
-	if r := recover(); r != nil {
-		l := defaultLogger.WithOptions(zap.AddCallerSkip(1))
-		l.Sugar().Panic(r) // no need to sync, this happens in core automatically
+// Replace sets the default logger used by the package and returns
+// a func to restore the previous default logger.
+func Replace(logger *zap.Logger) (restore func()) {
+	defaultsMu.Lock()
+	prev, _ := defaults.Load().(*loggers)
+	defaults.Store(&loggers{logger: logger, sugar: logger.Sugar()})
+	defaultsMu.Unlock()
+
+	return func() {
+		if prev != nil {
+			defaultsMu.Lock()
+			defaults.Store(prev)
+			defaultsMu.Unlock()
+		}
 	}
 }
+
+func defaultLogger() *zap.Logger {
+	return defaults.Load().(*loggers).logger
+}
+
+func defaultSugarLogger() *zap.SugaredLogger {
+	return defaults.Load().(*loggers).sugar
+}
@@ -1,6 +1,7 @@
 package log
 
 import (
+	"sync"
 	"testing"
 
 	"go.uber.org/zap"
@@ -32,7 +33,7 @@ func TestCapturePanic(t *testing.T) {
 		if logs[0].Message != "oh no" {
 			t.Errorf("expected 'oh no', got %v", logs[0].Message)
 		}
-		if logs[0].Caller.TrimmedPath() != "log/log_test.go:41" {
+		if logs[0].Caller.TrimmedPath() != "log/log_test.go:42" {
 			t.Errorf("file:line doesn't match, got %v", logs[0].Caller.TrimmedPath())
 		}
 	}()
@@ -40,3 +41,38 @@ func TestCapturePanic(t *testing.T) {
 	defer CapturePanic()
 	panic("oh no") // if line changes, update test above
 }
+
+func TestReplace(t *testing.T) {
+	obs := setTestLogger()
+
+	core, replaced := observer.New(zapcore.DebugLevel)
+	restore := Replace(zap.New(core))
+	Info("replaced")
+	restore()
+	Info("restored")
+
+	if replaced.Len() != 1 || replaced.All()[0].Message != "replaced" {
+		t.Errorf("expected 'replaced' log in replaced logger, got %v", replaced.All())
+	}
+	if obs.Len() != 1 || obs.All()[0].Message != "restored" {
+		t.Errorf("expected 'restored' log in previous logger, got %v", obs.All())
+	}
+}
+
+func TestReplaceConcurrently(t *testing.T) {
+	setTestLogger()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			Replace(zap.NewNop())()
+		}()
+		go func() {
+			defer wg.Done()
+			Info("hello")
+		}()
+	}
+	wg.Wait()
+}
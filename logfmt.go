@@ -0,0 +1,266 @@
+package log
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// registered at variable initialization, before the default logger is set up in init
+var _ = zap.RegisterEncoder("logfmt", func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	return NewLogfmtEncoder(cfg), nil
+})
+
+var logfmtPool = buffer.NewPool()
+
+// NewLogfmtEncoder returns an encoder that writes logfmt lines like
+//
+//	ts=2021-08-01T10:11:12.000Z level=info logger=db msg="query failed" table=users
+//
+// It's registered as "logfmt" encoding. Nested objects are flattened with
+// dotted keys, i.e. user.name=jane. Arrays and reflected values are written
+// as JSON, quoted like any other value.
+func NewLogfmtEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &logfmtEncoder{cfg: &cfg, buf: logfmtPool.Get()}
+}
+
+type logfmtEncoder struct {
+	cfg    *zapcore.EncoderConfig
+	buf    *buffer.Buffer
+	prefix string // of namespaces and nested objects, i.e. "user."
+}
+
+func (e *logfmtEncoder) Clone() zapcore.Encoder {
+	clone := &logfmtEncoder{cfg: e.cfg, buf: logfmtPool.Get(), prefix: e.prefix}
+	clone.buf.Write(e.buf.Bytes())
+	return clone
+}
+
+func (e *logfmtEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := &logfmtEncoder{cfg: e.cfg, buf: logfmtPool.Get()}
+
+	if e.cfg.TimeKey != "" {
+		if e.cfg.EncodeTime != nil {
+			final.addEncoded(e.cfg.TimeKey, func(arr zapcore.PrimitiveArrayEncoder) { e.cfg.EncodeTime(entry.Time, arr) })
+		} else {
+			final.AddString(e.cfg.TimeKey, entry.Time.Format(time.RFC3339Nano))
+		}
+	}
+	if e.cfg.LevelKey != "" {
+		if e.cfg.EncodeLevel != nil {
+			final.addEncoded(e.cfg.LevelKey, func(arr zapcore.PrimitiveArrayEncoder) { e.cfg.EncodeLevel(entry.Level, arr) })
+		} else {
+			final.AddString(e.cfg.LevelKey, entry.Level.String())
+		}
+	}
+	if entry.LoggerName != "" && e.cfg.NameKey != "" {
+		if e.cfg.EncodeName != nil {
+			final.addEncoded(e.cfg.NameKey, func(arr zapcore.PrimitiveArrayEncoder) { e.cfg.EncodeName(entry.LoggerName, arr) })
+		} else {
+			final.AddString(e.cfg.NameKey, entry.LoggerName)
+		}
+	}
+	if entry.Caller.Defined && e.cfg.CallerKey != "" {
+		if e.cfg.EncodeCaller != nil {
+			final.addEncoded(e.cfg.CallerKey, func(arr zapcore.PrimitiveArrayEncoder) { e.cfg.EncodeCaller(entry.Caller, arr) })
+		} else {
+			final.AddString(e.cfg.CallerKey, entry.Caller.String())
+		}
+	}
+	if e.cfg.MessageKey != "" {
+		final.AddString(e.cfg.MessageKey, entry.Message)
+	}
+
+	// context and fields
+	if e.buf.Len() > 0 {
+		final.separate()
+		final.buf.Write(e.buf.Bytes())
+	}
+	final.prefix = e.prefix
+	for _, f := range fields {
+		f.AddTo(final)
+	}
+	final.prefix = ""
+
+	if entry.Stack != "" && e.cfg.StacktraceKey != "" {
+		final.AddString(e.cfg.StacktraceKey, entry.Stack)
+	}
+
+	if e.cfg.LineEnding != "" {
+		final.buf.AppendString(e.cfg.LineEnding)
+	} else {
+		final.buf.AppendString(zapcore.DefaultLineEnding)
+	}
+	return final.buf, nil
+}
+
+func (e *logfmtEncoder) AddArray(key string, v zapcore.ArrayMarshaler) error {
+	return e.addJSON(key, func(enc zapcore.ObjectEncoder) error { return enc.AddArray("_", v) })
+}
+
+func (e *logfmtEncoder) AddObject(key string, v zapcore.ObjectMarshaler) error {
+	// flatten with dotted keys
+	prefix := e.prefix
+	e.prefix = e.prefix + key + "."
+	err := v.MarshalLogObject(e)
+	e.prefix = prefix
+	return err
+}
+
+func (e *logfmtEncoder) AddReflected(key string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		e.AddString(key, s) // don't quote strings twice
+		return nil
+	}
+	e.addValue(key, string(b))
+	return nil
+}
+
+func (e *logfmtEncoder) OpenNamespace(key string) {
+	e.prefix = e.prefix + key + "."
+}
+
+func (e *logfmtEncoder) AddBinary(key string, v []byte) {
+	e.AddString(key, base64.StdEncoding.EncodeToString(v))
+}
+
+func (e *logfmtEncoder) AddByteString(key string, v []byte) { e.AddString(key, string(v)) }
+func (e *logfmtEncoder) AddBool(key string, v bool)         { e.addValue(key, strconv.FormatBool(v)) }
+func (e *logfmtEncoder) AddComplex128(key string, v complex128) {
+	e.addValue(key, strconv.FormatComplex(v, 'f', -1, 128))
+}
+func (e *logfmtEncoder) AddComplex64(key string, v complex64) {
+	e.addValue(key, strconv.FormatComplex(complex128(v), 'f', -1, 64))
+}
+func (e *logfmtEncoder) AddFloat64(key string, v float64) { e.addValue(key, formatFloat(v, 64)) }
+func (e *logfmtEncoder) AddFloat32(key string, v float32) {
+	e.addValue(key, formatFloat(float64(v), 32))
+}
+func (e *logfmtEncoder) AddInt(key string, v int)         { e.AddInt64(key, int64(v)) }
+func (e *logfmtEncoder) AddInt64(key string, v int64)     { e.addValue(key, strconv.FormatInt(v, 10)) }
+func (e *logfmtEncoder) AddInt32(key string, v int32)     { e.AddInt64(key, int64(v)) }
+func (e *logfmtEncoder) AddInt16(key string, v int16)     { e.AddInt64(key, int64(v)) }
+func (e *logfmtEncoder) AddInt8(key string, v int8)       { e.AddInt64(key, int64(v)) }
+func (e *logfmtEncoder) AddUint(key string, v uint)       { e.AddUint64(key, uint64(v)) }
+func (e *logfmtEncoder) AddUint64(key string, v uint64)   { e.addValue(key, strconv.FormatUint(v, 10)) }
+func (e *logfmtEncoder) AddUint32(key string, v uint32)   { e.AddUint64(key, uint64(v)) }
+func (e *logfmtEncoder) AddUint16(key string, v uint16)   { e.AddUint64(key, uint64(v)) }
+func (e *logfmtEncoder) AddUint8(key string, v uint8)     { e.AddUint64(key, uint64(v)) }
+func (e *logfmtEncoder) AddUintptr(key string, v uintptr) { e.AddUint64(key, uint64(v)) }
+func (e *logfmtEncoder) AddString(key, v string)          { e.addValue(key, v) }
+
+func (e *logfmtEncoder) AddDuration(key string, v time.Duration) {
+	if e.cfg.EncodeDuration == nil {
+		e.AddString(key, v.String())
+		return
+	}
+	e.addEncoded(key, func(arr zapcore.PrimitiveArrayEncoder) { e.cfg.EncodeDuration(v, arr) })
+}
+
+func (e *logfmtEncoder) AddTime(key string, v time.Time) {
+	if e.cfg.EncodeTime == nil {
+		e.AddString(key, v.Format(time.RFC3339Nano))
+		return
+	}
+	e.addEncoded(key, func(arr zapcore.PrimitiveArrayEncoder) { e.cfg.EncodeTime(v, arr) })
+}
+
+// addEncoded adds the output of one of the encoders of EncoderConfig.
+func (e *logfmtEncoder) addEncoded(key string, encode func(zapcore.PrimitiveArrayEncoder)) {
+	arr := &stringArray{}
+	encode(arr)
+	e.addValue(key, arr.join())
+}
+
+// addJSON adds a value as JSON, as encoded by zap's JSON encoder under the key "_".
+func (e *logfmtEncoder) addJSON(key string, add func(zapcore.ObjectEncoder) error) error {
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+		EncodeTime:     e.cfg.EncodeTime,
+		EncodeDuration: e.cfg.EncodeDuration,
+	})
+	if err := add(enc); err != nil {
+		return err
+	}
+	buf, err := enc.EncodeEntry(zapcore.Entry{}, nil)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	// {"_":[...]}\n
+	js := strings.TrimSpace(buf.String())
+	e.addValue(key, js[len(`{"_":`):len(js)-1])
+	return nil
+}
+
+// addValue writes key=value, quoting value if necessary.
+func (e *logfmtEncoder) addValue(key, value string) {
+	e.separate()
+	writeLogfmtKey(e.buf, e.prefix+key)
+	e.buf.AppendByte('=')
+	if needsQuotes(value) {
+		e.buf.AppendString(strconv.Quote(value))
+	} else {
+		e.buf.AppendString(value)
+	}
+}
+
+func (e *logfmtEncoder) separate() {
+	if e.buf.Len() > 0 {
+		e.buf.AppendByte(' ')
+	}
+}
+
+// writeLogfmtKey replaces characters not allowed in keys with underscores.
+func writeLogfmtKey(buf *buffer.Buffer, key string) {
+	if key == "" {
+		buf.AppendByte('_')
+		return
+	}
+	for _, r := range key {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			buf.AppendByte('_')
+		} else {
+			buf.AppendString(string(r))
+		}
+	}
+}
+
+func needsQuotes(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatFloat formats floats like zap's JSON encoder.
+func formatFloat(v float64, bitSize int) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'f', -1, bitSize)
+}
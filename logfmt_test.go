@@ -0,0 +1,84 @@
+package log
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type testUserObject struct{}
+
+func (testUserObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", "jane doe")
+	return enc.AddObject("address", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		enc.AddString("city", "Berlin")
+		return nil
+	}))
+}
+
+func TestLogfmtEncoder(t *testing.T) {
+	cfg := zap.NewProductionEncoderConfig()
+	cfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	enc := NewLogfmtEncoder(cfg)
+	enc.AddString("service", "api")
+
+	entry := zapcore.Entry{
+		Level:      zapcore.InfoLevel,
+		Time:       time.Date(2021, 8, 1, 10, 11, 12, 0, time.UTC),
+		LoggerName: "db",
+		Message:    `query "users" failed`,
+		Stack:      "main.main\n\tmain.go:10",
+	}
+	fields := []zapcore.Field{
+		zap.String("empty", ""),
+		zap.String("key with=space", "a\\b"),
+		zap.Int("rows", 3),
+		zap.Float64("ratio", 0.5),
+		zap.Duration("took", 1500*time.Millisecond),
+		zap.Strings("tags", []string{"a", "b c"}),
+		zap.Ints("ids", []int{1, 2}),
+		zap.Object("user", testUserObject{}),
+		zap.Reflect("meta", map[string]int{"x": 1}),
+		zap.Namespace("http"),
+		zap.Int("status", 500),
+	}
+
+	buf, err := enc.EncodeEntry(entry, fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := `ts=2021-08-01T10:11:12.000Z level=info logger=db msg="query \"users\" failed" service=api ` +
+		`empty="" key_with_space="a\\b" rows=3 ratio=0.5 took=1.5 tags="[\"a\",\"b c\"]" ids=[1,2] ` +
+		`user.name="jane doe" user.address.city=Berlin meta="{\"x\":1}" http.status=500 ` +
+		`stacktrace="main.main\n\tmain.go:10"` + "\n"
+	if buf.String() != expect {
+		t.Errorf("expected\n%v\ngot\n%v", expect, buf.String())
+	}
+}
+
+func TestLogfmtEncoding(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	cfg := zap.NewProductionConfig()
+	cfg.Encoding = "logfmt"
+	cfg.OutputPaths = []string{path}
+	cfg.EncoderConfig.TimeKey = ""
+	cfg.EncoderConfig.CallerKey = ""
+
+	logger, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.With(zap.String("a", "b")).Info("hello")
+	logger.Sync()
+
+	body, _ := ioutil.ReadFile(path)
+	if string(body) != "level=info msg=hello a=b\n" {
+		t.Errorf("unexpected output %q", body)
+	}
+}
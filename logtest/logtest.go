@@ -0,0 +1,173 @@
+// Package logtest installs an observed default logger for the duration
+// of a test and provides helpers to assert on the logged entries.
+//
+//	func TestSomething(t *testing.T) {
+//		logs := logtest.Observe(t, logtest.Mirror())
+//		doSomething()
+//		logs.AssertLogged(t, zapcore.InfoLevel, "done", zap.Int("items", 3))
+//		logs.AssertNoErrors(t)
+//	}
+package logtest
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mattes/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type options struct {
+	level  zapcore.LevelEnabler
+	mirror bool
+}
+
+type Option func(*options)
+
+// Level sets the minimum level that is observed, defaults to debug.
+func Level(level zapcore.LevelEnabler) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+// Mirror writes all logs to t.Log as well,
+// which go test shows for failing tests or with -v.
+func Mirror() Option {
+	return func(o *options) {
+		o.mirror = true
+	}
+}
+
+// Logs are the entries observed since Observe was called.
+type Logs struct {
+	*observer.ObservedLogs
+	logger *zap.Logger
+}
+
+// Observe replaces the default logger with an observed logger,
+// until the test and all its subtests have finished.
+// Tests using Observe can't run in parallel.
+func Observe(t testing.TB, opts ...Option) *Logs {
+	o := options{level: zapcore.DebugLevel}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	core, obs := observer.New(o.level)
+	if o.mirror {
+		mirror := zaptest.NewLogger(t, zaptest.Level(o.level)).Core()
+		core = zapcore.NewTee(core, mirror)
+	}
+
+	// options should resemble the default logger options
+	logger := zap.New(core, zap.AddCaller())
+	t.Cleanup(log.Replace(logger.WithOptions(zap.AddCallerSkip(1))))
+
+	return &Logs{ObservedLogs: obs, logger: logger}
+}
+
+// Logger returns a logger writing to the observed logs,
+// to be passed to code that doesn't use the default logger.
+func (l *Logs) Logger() *zap.Logger {
+	return l.logger
+}
+
+// Match describes an expected entry. Message matches if it's a substring
+// of the entry's message. Fields must be present with equal values,
+// other fields of the entry are ignored.
+type Match struct {
+	Level   zapcore.Level
+	Message string
+	Fields  []zap.Field
+}
+
+func (m Match) matches(e observer.LoggedEntry) bool {
+	if e.Level != m.Level || !strings.Contains(e.Message, m.Message) {
+		return false
+	}
+	if len(m.Fields) == 0 {
+		return true
+	}
+
+	expect := zapcore.NewMapObjectEncoder()
+	for _, f := range m.Fields {
+		f.AddTo(expect)
+	}
+
+	got := e.ContextMap()
+	for k, v := range expect.Fields {
+		if gv, ok := got[k]; !ok || !reflect.DeepEqual(gv, v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Match) String() string {
+	s := fmt.Sprintf("%v %q", m.Level, m.Message)
+	if len(m.Fields) > 0 {
+		expect := zapcore.NewMapObjectEncoder()
+		for _, f := range m.Fields {
+			f.AddTo(expect)
+		}
+		s += fmt.Sprintf(" %v", expect.Fields)
+	}
+	return s
+}
+
+// AssertLogged fails t, unless an entry with level, a message
+// containing msg and the given fields was logged.
+func (l *Logs) AssertLogged(t testing.TB, level zapcore.Level, msg string, fields ...zap.Field) {
+	t.Helper()
+
+	m := Match{Level: level, Message: msg, Fields: fields}
+	for _, e := range l.All() {
+		if m.matches(e) {
+			return
+		}
+	}
+	t.Errorf("expected log %v, got:\n%v", m, l)
+}
+
+// AssertNoErrors fails t, if an entry at error level or above was logged.
+func (l *Logs) AssertNoErrors(t testing.TB) {
+	t.Helper()
+
+	for _, e := range l.All() {
+		if e.Level >= zapcore.ErrorLevel {
+			t.Errorf("expected no errors, got:\n%v", l)
+			return
+		}
+	}
+}
+
+// AssertInOrder fails t, unless entries matching all matches were logged
+// in the given order. Other entries in between are ignored.
+func (l *Logs) AssertInOrder(t testing.TB, matches ...Match) {
+	t.Helper()
+
+	i := 0
+	for _, e := range l.All() {
+		if i < len(matches) && matches[i].matches(e) {
+			i++
+		}
+	}
+	if i < len(matches) {
+		t.Errorf("expected log %v after %v matched logs, got:\n%v", matches[i], i, l)
+	}
+}
+
+// String returns all observed entries, one per line.
+func (l *Logs) String() string {
+	var b strings.Builder
+	for _, e := range l.All() {
+		fmt.Fprintf(&b, "  %v %q %v\n", e.Level, e.Message, e.ContextMap())
+	}
+	return b.String()
+}
@@ -0,0 +1,76 @@
+package logtest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mattes/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fakeT records failures instead of failing the test
+type fakeT struct {
+	testing.TB
+	failed bool
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.failed = true
+}
+
+func TestObserve(t *testing.T) {
+	logs := Observe(t, Mirror())
+
+	log.Infow("started", "items", 3)
+	log.Warn("slow")
+	logs.Logger().Info("done", zap.String("status", "ok"))
+
+	logs.AssertLogged(t, zapcore.InfoLevel, "start", zap.Int("items", 3))
+	logs.AssertLogged(t, zapcore.InfoLevel, "done")
+	logs.AssertNoErrors(t)
+	logs.AssertInOrder(t,
+		Match{Level: zapcore.InfoLevel, Message: "started"},
+		Match{Level: zapcore.InfoLevel, Message: "done", Fields: []zap.Field{zap.String("status", "ok")}},
+	)
+
+	tt := map[string]func(t testing.TB){
+		"wrong level": func(t testing.TB) { logs.AssertLogged(t, zapcore.ErrorLevel, "started") },
+		"wrong field": func(t testing.TB) { logs.AssertLogged(t, zapcore.InfoLevel, "started", zap.Int("items", 4)) },
+		"wrong order": func(t testing.TB) {
+			logs.AssertInOrder(t, Match{Level: zapcore.InfoLevel, Message: "done"}, Match{Level: zapcore.InfoLevel, Message: "started"})
+		},
+		"missing":      func(t testing.TB) { logs.AssertLogged(t, zapcore.InfoLevel, "missing") },
+		"extra fields": func(t testing.TB) { logs.AssertLogged(t, zapcore.InfoLevel, "done", zap.Bool("extra", true)) },
+	}
+
+	for name, fn := range tt {
+		ft := &fakeT{TB: t}
+		fn(ft)
+		if !ft.failed {
+			t.Errorf("%v: expected failure", name)
+		}
+	}
+
+	log.Error(errors.New("failed"))
+	ft := &fakeT{TB: t}
+	logs.AssertNoErrors(ft)
+	if !ft.failed {
+		t.Errorf("expected AssertNoErrors to fail")
+	}
+}
+
+func TestObserveRestores(t *testing.T) {
+	var logs *Logs
+	t.Run("sub", func(t *testing.T) {
+		logs = Observe(t)
+		log.Info("inside")
+	})
+	log.Info("outside")
+
+	if logs.Len() != 1 {
+		t.Errorf("expected default logger to be restored, got %v", logs.All())
+	}
+}
@@ -0,0 +1,133 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// CapturePanic captures, logs and re-throws a panic.
+// Only useful when used with defer.
+func CapturePanic() {
+	if r := recover(); r != nil {
+		logPanic(defaultLogger(), zapcore.PanicLevel, r) // no need to sync, this happens in core automatically
+	}
+}
+
+// RecoverAndLog captures and logs a panic at error level, without re-throwing it.
+// Only useful when used with defer, i.e. in worker loops:
+//
+//	for job := range jobs {
+//		func() {
+//			defer log.RecoverAndLog()
+//			job.Run()
+//		}()
+//	}
+func RecoverAndLog() {
+	if r := recover(); r != nil {
+		logPanic(defaultLogger(), zapcore.ErrorLevel, r)
+	}
+}
+
+// Go runs fn in a new goroutine. A panic in fn is logged at error level
+// and doesn't crash the program.
+func Go(fn func()) {
+	go func() {
+		defer RecoverAndLog()
+		fn()
+	}()
+}
+
+// SafeGo runs fn in a new goroutine and passes ctx on. A panic in fn is
+// logged at error level with the fields attached to ctx (see WithContext)
+// and doesn't crash the program.
+func SafeGo(ctx context.Context, fn func(ctx context.Context)) {
+	go func() {
+		defer recoverAndLogCtx(ctx)
+		fn(ctx)
+	}()
+}
+
+func recoverAndLogCtx(ctx context.Context) {
+	if r := recover(); r != nil {
+		logPanic(contextLogger(ctx).Desugar(), zapcore.ErrorLevel, r)
+	}
+}
+
+// logPanic logs the recovered value r. It must be called by the deferred
+// func that recovered, while the panicking goroutine's stack is still intact.
+// The entry's caller is set to where the panic happened and its stack
+// to the full goroutine stack.
+func logPanic(logger *zap.Logger, level zapcore.Level, r interface{}) {
+	msg := fmt.Sprint(r)
+	fields := []zap.Field{zap.String("panicType", fmt.Sprintf("%T", r))}
+
+	if err, ok := r.(error); ok {
+		msg = err.Error()
+		fields = append(fields, Err(err))
+	}
+
+	if ce := logger.Check(level, msg); ce != nil {
+		caller, stack := panicStack()
+		if ce.Entry.Caller.Defined {
+			ce.Entry.Caller = caller
+		}
+		ce.Entry.Stack = stack
+		ce.Write(fields...) // panics for zapcore.PanicLevel
+	}
+}
+
+// panicStack returns the frame where the current panic happened and the
+// stack from there on, formatted like zap formats stacktraces.
+func panicStack() (zapcore.EntryCaller, string) {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs) // skip runtime.Callers, panicStack and logPanic
+	for n == len(pcs) {
+		pcs = make([]uintptr, len(pcs)*2)
+		n = runtime.Callers(3, pcs)
+	}
+
+	// find the frames after runtime.gopanic and its helpers,
+	// i.e. runtime.panicmem or runtime.goPanicIndex
+	all := collectFrames(pcs[:n])
+	frames := all
+	for i, f := range all {
+		if f.Function == "runtime.gopanic" {
+			frames = all[i+1:]
+			for len(frames) > 0 && strings.HasPrefix(frames[0].Function, "runtime.") {
+				frames = frames[1:]
+			}
+			break
+		}
+	}
+	if len(frames) == 0 {
+		return zapcore.EntryCaller{}, ""
+	}
+
+	var b strings.Builder
+	for i, f := range frames {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "%s\n\t%s:%d", f.Function, f.File, f.Line)
+	}
+
+	f := frames[0]
+	return zapcore.NewEntryCaller(f.PC, f.File, f.Line, true), b.String()
+}
+
+func collectFrames(pcs []uintptr) []runtime.Frame {
+	list := make([]runtime.Frame, 0, len(pcs))
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		list = append(list, f)
+		if !more {
+			return list
+		}
+	}
+}
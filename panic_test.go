@@ -0,0 +1,97 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRecoverAndLog(t *testing.T) {
+	obs := setTestLogger()
+
+	func() {
+		defer RecoverAndLog()
+		panic(fmt.Errorf("job failed: %w", errors.New("connection reset")))
+	}()
+
+	logs := obs.TakeAll()
+	if len(logs) != 1 {
+		t.Fatalf("expected a log, got %v", len(logs))
+	}
+	l := logs[0]
+
+	if l.Level != zapcore.ErrorLevel || l.Message != "job failed: connection reset" {
+		t.Errorf("unexpected entry %v %q", l.Level, l.Message)
+	}
+	if filepath.Base(l.Caller.File) != "panic_test.go" {
+		t.Errorf("expected caller panic_test.go, got %v", l.Caller.File)
+	}
+	if !strings.HasPrefix(l.Stack, "github.com/mattes/log.TestRecoverAndLog") {
+		t.Errorf("expected stack to start at the panic, got %v", l.Stack)
+	}
+
+	ctx := l.ContextMap()
+	if ctx["panicType"] != "*fmt.wrapError" {
+		t.Errorf("unexpected panicType %v", ctx["panicType"])
+	}
+	chain, _ := ctx["errorCauses"].([]interface{})
+	if len(chain) != 2 || chain[1].(map[string]interface{})["message"] != "connection reset" {
+		t.Errorf("unexpected errorCauses %v", ctx["errorCauses"])
+	}
+}
+
+func TestRecoverAndLogRuntimeError(t *testing.T) {
+	obs := setTestLogger()
+
+	func() {
+		defer RecoverAndLog()
+		var m map[string]int
+		m["a"] = 1
+	}()
+
+	logs := obs.TakeAll()
+	if len(logs) != 1 {
+		t.Fatalf("expected a log, got %v", len(logs))
+	}
+	if filepath.Base(logs[0].Caller.File) != "panic_test.go" {
+		t.Errorf("expected caller panic_test.go, got %v", logs[0].Caller.File)
+	}
+}
+
+func TestGo(t *testing.T) {
+	obs := setTestLogger()
+
+	Go(func() {
+		panic("go")
+	})
+
+	ctx := WithContext(context.Background(), "request", "1")
+	SafeGo(ctx, func(ctx context.Context) {
+		panic("safe go")
+	})
+
+	// the panics are logged after fn returns
+	deadline := time.Now().Add(time.Second)
+	for obs.Len() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	logs := obs.TakeAll()
+	if len(logs) != 2 {
+		t.Fatalf("expected two logs, got %v", len(logs))
+	}
+	for _, l := range logs {
+		if l.Message == "safe go" && l.ContextMap()["request"] != "1" {
+			t.Errorf("expected context fields, got %v", l.ContextMap())
+		}
+		if l.ContextMap()["panicType"] != "string" {
+			t.Errorf("unexpected panicType %v", l.ContextMap()["panicType"])
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package log
+
+import (
+	"fmt"
+	"runtime"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func NewProductionEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		FunctionKey:    zapcore.OmitKey,
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.SecondsDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+}
+
+// NewProductionConfig returns a config that writes JSON logs at info level
+// to stderr, with stacktraces for errors and sampling.
+func NewProductionConfig() zap.Config {
+	return zap.Config{
+		Level:             zap.NewAtomicLevelAt(zap.InfoLevel),
+		Development:       false,
+		DisableCaller:     false,
+		DisableStacktrace: false, // zap records stacktraces at error level in production
+		Sampling: &zap.SamplingConfig{
+			Initial:    100,
+			Thereafter: 100,
+		},
+		Encoding:         "json",
+		EncoderConfig:    NewProductionEncoderConfig(),
+		OutputPaths:      []string{"stderr"},
+		ErrorOutputPaths: []string{"stderr"},
+		InitialFields:    map[string]interface{}{},
+	}
+}
+
+// FieldNaming is a naming scheme for the keys of the entry fields,
+// like time, level and message.
+type FieldNaming string
+
+const (
+	// FieldNamingZap uses zap's default keys, i.e. ts, level, msg.
+	FieldNamingZap FieldNaming = "zap"
+
+	// FieldNamingECS uses the keys of the Elastic Common Schema, i.e. @timestamp, log.level, message.
+	FieldNamingECS FieldNaming = "ecs"
+
+	// FieldNamingOTel uses the OpenTelemetry log data model and semantic conventions,
+	// i.e. timestamp, severity_text, body, code.filepath.
+	FieldNamingOTel FieldNaming = "otel"
+
+	// FieldNamingGCP uses the special keys of Google Cloud structured logging,
+	// i.e. timestamp, severity, message, logging.googleapis.com/sourceLocation.
+	FieldNamingGCP FieldNaming = "gcp"
+)
+
+// Apply sets the keys and encoders of cfg.EncoderConfig according to the naming scheme.
+//
+//	cfg := log.NewProductionConfig()
+//	err := log.FieldNamingECS.Apply(&cfg)
+func (n FieldNaming) Apply(cfg *zap.Config) error {
+	enc := &cfg.EncoderConfig
+
+	switch n {
+	case FieldNamingZap:
+		keys := NewProductionEncoderConfig()
+		enc.TimeKey, enc.LevelKey, enc.NameKey = keys.TimeKey, keys.LevelKey, keys.NameKey
+		enc.CallerKey, enc.FunctionKey = keys.CallerKey, keys.FunctionKey
+		enc.MessageKey, enc.StacktraceKey = keys.MessageKey, keys.StacktraceKey
+
+	case FieldNamingECS:
+		enc.TimeKey = "@timestamp"
+		enc.LevelKey = "log.level"
+		enc.NameKey = "log.logger"
+		enc.CallerKey = "log.origin.file.name"
+		enc.FunctionKey = "log.origin.function"
+		enc.MessageKey = "message"
+		enc.StacktraceKey = "error.stack_trace"
+		enc.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+		enc.EncodeLevel = zapcore.LowercaseLevelEncoder
+
+		if cfg.InitialFields == nil {
+			cfg.InitialFields = make(map[string]interface{})
+		}
+		cfg.InitialFields["ecs.version"] = "1.6.0"
+
+	case FieldNamingOTel:
+		enc.TimeKey = "timestamp"
+		enc.LevelKey = "severity_text"
+		enc.NameKey = "otel.scope.name"
+		enc.CallerKey = "code.filepath"
+		enc.FunctionKey = "code.function"
+		enc.MessageKey = "body"
+		enc.StacktraceKey = "exception.stacktrace"
+		enc.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+		enc.EncodeLevel = zapcore.CapitalLevelEncoder
+
+	case FieldNamingGCP:
+		enc.TimeKey = "timestamp"
+		enc.LevelKey = "severity"
+		enc.NameKey = "logger"
+		enc.CallerKey = "logging.googleapis.com/sourceLocation"
+		enc.FunctionKey = zapcore.OmitKey // part of sourceLocation
+		enc.MessageKey = "message"
+		enc.StacktraceKey = "stack_trace"
+		enc.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+		enc.EncodeLevel = gcpSeverityEncoder
+		enc.EncodeCaller = gcpSourceLocationEncoder
+
+	default:
+		return fmt.Errorf("unknown field naming %q", n)
+	}
+
+	return nil
+}
+
+// gcpSeverity mirrors the severities of the googleStackdriver package
+var gcpSeverity = map[zapcore.Level]string{
+	zapcore.DebugLevel:  "DEBUG",
+	zapcore.InfoLevel:   "INFO",
+	zapcore.WarnLevel:   "WARNING",
+	zapcore.ErrorLevel:  "ERROR",
+	zapcore.DPanicLevel: "CRITICAL",
+	zapcore.PanicLevel:  "CRITICAL",
+	zapcore.FatalLevel:  "CRITICAL",
+}
+
+func gcpSeverityEncoder(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	if s, ok := gcpSeverity[l]; ok {
+		enc.AppendString(s)
+		return
+	}
+	enc.AppendString("DEFAULT")
+}
+
+// gcpSourceLocationEncoder writes the caller as sourceLocation object,
+// if the encoder supports objects, i.e. the JSON encoder.
+func gcpSourceLocationEncoder(caller zapcore.EntryCaller, enc zapcore.PrimitiveArrayEncoder) {
+	arr, ok := enc.(zapcore.ArrayEncoder)
+	if !ok {
+		zapcore.ShortCallerEncoder(caller, enc)
+		return
+	}
+
+	arr.AppendObject(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		enc.AddString("file", caller.File)
+		enc.AddInt("line", caller.Line)
+		if fn := callerFunction(caller); fn != "" {
+			enc.AddString("function", fn)
+		}
+		return nil
+	}))
+}
+
+func callerFunction(caller zapcore.EntryCaller) string {
+	if caller.Function != "" {
+		return caller.Function
+	}
+	if f := runtime.FuncForPC(caller.PC); f != nil {
+		return f.Name()
+	}
+	return ""
+}
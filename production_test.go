@@ -0,0 +1,71 @@
+package log
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestFieldNaming(t *testing.T) {
+	tt := map[FieldNaming][]string{
+		FieldNamingZap:  {"ts", "level", "msg", "caller", "stacktrace"},
+		FieldNamingECS:  {"@timestamp", "log.level", "message", "log.origin.file.name", "log.origin.function", "error.stack_trace", "ecs.version"},
+		FieldNamingOTel: {"timestamp", "severity_text", "body", "code.filepath", "code.function", "exception.stacktrace"},
+		FieldNamingGCP:  {"timestamp", "severity", "message", "logging.googleapis.com/sourceLocation", "stack_trace"},
+	}
+
+	for naming, keys := range tt {
+		path := filepath.Join(t.TempDir(), "app.log")
+
+		cfg := NewProductionConfig()
+		cfg.OutputPaths = []string{path}
+		if err := naming.Apply(&cfg); err != nil {
+			t.Fatal(err)
+		}
+
+		logger, err := cfg.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		logger.Error("failed")
+		logger.Sync()
+
+		body, _ := ioutil.ReadFile(path)
+		entry := make(map[string]interface{})
+		if err := json.Unmarshal(body, &entry); err != nil {
+			t.Fatalf("%v: %v", naming, err)
+		}
+
+		for _, k := range keys {
+			if _, ok := entry[k]; !ok {
+				t.Errorf("%v: missing key %v in %v", naming, k, entry)
+			}
+		}
+
+		if naming == FieldNamingGCP {
+			loc, _ := entry["logging.googleapis.com/sourceLocation"].(map[string]interface{})
+			if entry["severity"] != "ERROR" || filepath.Base(loc["file"].(string)) != "production_test.go" || loc["function"] != "github.com/mattes/log.TestFieldNaming" {
+				t.Errorf("unexpected gcp entry %v", entry)
+			}
+		}
+	}
+
+	cfg := NewProductionConfig()
+	if err := FieldNaming("unknown").Apply(&cfg); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestNewEnvConfigFieldNaming(t *testing.T) {
+	setEnv(t, EnvDevelopment, "false")
+	setEnv(t, EnvFieldNaming, "ECS")
+
+	cfg, err := NewEnvConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.EncoderConfig.MessageKey != "message" {
+		t.Errorf("expected ecs keys, got %+v", cfg.EncoderConfig)
+	}
+}
@@ -3,6 +3,7 @@ package prometheus
 import (
 	"strings"
 
+	"github.com/mattes/log"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap/zapcore"
 )
@@ -36,14 +37,22 @@ func Inc(name string, help ...string) zapcore.Field {
 }
 
 type Config struct {
-	Registerer prometheus.Registerer
+	Registerer prometheus.Registerer `yaml:"-"`
 
-	Namespace string
-	Subsystem string
+	Namespace string `yaml:"namespace"`
+	Subsystem string `yaml:"subsystem"`
 
 	// If Inc() is used without a help text and UseMessageAsHelp
 	// is set to true (default false), the logged message will be used as help text.
-	UseMessageAsHelp bool
+	UseMessageAsHelp bool `yaml:"useMessageAsHelp"`
+}
+
+// register as "prometheus" output for log.LoadConfig
+func init() {
+	log.RegisterOutput("prometheus", func() log.CoreBuilder {
+		c := NewConfig()
+		return &c
+	})
 }
 
 func NewConfig() Config {
@@ -0,0 +1,351 @@
+package log
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"runtime"
+	"sort"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Value patterns for RedactConfig.Values
+const (
+	RedactEmails        = `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`
+	RedactCardNumbers   = `\b(?:4\d{3}|5[1-5]\d{2}|3[47]\d{2}|6011)(?:[ -]?\d{4}){2}[ -]?\d{3,4}\b`
+	RedactBearerTokens  = `(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`
+	RedactGoogleAPIKeys = `AIza[0-9A-Za-z\-_]{35}`
+	RedactPrivateKeys   = `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`
+)
+
+// redacted replaces redacted values, unless they are hashed
+const redacted = "[REDACTED]"
+
+type RedactConfig struct {
+	// Keys are regular expressions matched against field keys.
+	// Values of matching fields are redacted entirely.
+	Keys []string
+
+	// Values are regular expressions matched against messages and string
+	// values of all fields, matches are redacted.
+	Values []string
+
+	// Hash replaces redacted values with a hash instead of [REDACTED],
+	// so equal values can still be correlated.
+	Hash bool
+
+	// HashKey is the HMAC key for hashes. Without key, hashes of
+	// guessable values like emails can be reversed.
+	HashKey string
+}
+
+func NewRedactConfig() RedactConfig {
+	return RedactConfig{
+		Keys: []string{`(?i)passw(or)?d|secret|token|authorization|api[-_]?key|cookie|credential`},
+		Values: []string{
+			RedactEmails,
+			RedactCardNumbers,
+			RedactBearerTokens,
+			RedactGoogleAPIKeys,
+			RedactPrivateKeys,
+		},
+	}
+}
+
+// Build wraps core with a Redactor.
+func (cfg RedactConfig) Build(core zapcore.Core) (*Redactor, error) {
+	r := &Redactor{Core: core, hash: cfg.Hash, hashKey: []byte(cfg.HashKey)}
+
+	for _, k := range cfg.Keys {
+		re, err := regexp.Compile(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Keys: %v", err)
+		}
+		r.keys = append(r.keys, re)
+	}
+
+	for _, v := range cfg.Values {
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Values: %v", err)
+		}
+		r.values = append(r.values, re)
+	}
+
+	return r, nil
+}
+
+// Redactor is a core that redacts secrets and personal data from messages,
+// fields and context fields, before the wrapped core sees them.
+// It should wrap all other cores, i.e.
+//
+//	c := log.NewRedactConfig()
+//	c.Keys = append(c.Keys, `(?i)^ssn$`)
+//	r, err := c.Build(zapcore.NewTee(cores...))
+//	logger := zap.New(r)
+//
+// Errors keep their causes, types and stacks, only their messages are
+// redacted. Fields of custom types, like googleStackdriver.Request,
+// aren't redacted.
+type Redactor struct {
+	zapcore.Core
+
+	keys    []*regexp.Regexp
+	values  []*regexp.Regexp
+	hash    bool
+	hashKey []byte
+}
+
+func (r *Redactor) With(fields []zapcore.Field) zapcore.Core {
+	clone := *r
+	clone.Core = r.Core.With(r.redactFields(fields))
+	return &clone
+}
+
+func (r *Redactor) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checkWrapped(r.Core, entry, checkedEntry, r.write)
+}
+
+func (r *Redactor) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return r.write(entry, fields, r.Core.Write)
+}
+
+func (r *Redactor) write(entry zapcore.Entry, fields []zapcore.Field, next writeFunc) error {
+	entry.Message = r.redactString(entry.Message)
+	return next(entry, r.redactFields(fields))
+}
+
+// redactFields returns fields with redacted values,
+// fields is copied if anything changes.
+func (r *Redactor) redactFields(fields []zapcore.Field) []zapcore.Field {
+	out := fields
+	copied := false
+	for i, f := range fields {
+		rf, changed := r.redactField(f)
+		if !changed {
+			continue
+		}
+		if !copied {
+			out = append([]zapcore.Field(nil), fields...)
+			copied = true
+		}
+		out[i] = rf
+	}
+	return out
+}
+
+func (r *Redactor) redactField(f zapcore.Field) (zapcore.Field, bool) {
+	switch f.Type {
+	case zapcore.SkipType, zapcore.NamespaceType:
+		return f, false
+	}
+
+	if r.matchKey(f.Key) {
+		return zap.String(f.Key, r.replace(fieldString(f))), true
+	}
+
+	switch f.Type {
+	case zapcore.StringType:
+		if s := r.redactString(f.String); s != f.String {
+			return zap.String(f.Key, s), true
+		}
+
+	case zapcore.ByteStringType:
+		if b, ok := f.Interface.([]byte); ok {
+			if s := r.redactString(string(b)); s != string(b) {
+				return zap.String(f.Key, s), true
+			}
+		}
+
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok {
+			// keep the error, unless a message in its chain has to change
+			if err, changed := r.redactError(err); changed {
+				return zap.NamedError(f.Key, err), true
+			}
+		}
+
+	case zapcore.InlineMarshalerType:
+		if e, ok := f.Interface.(structuredError); ok {
+			if err, changed := r.redactError(e.err); changed {
+				e.err = err
+				f.Interface = e
+				return f, true
+			}
+			return f, false
+		}
+
+		// fields of inline objects are added next to the others
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		if v, changed := r.redactValue(jsonValue(enc.Fields)); changed {
+			if m, ok := v.(map[string]interface{}); ok {
+				return zap.Inline(redactedObject(m)), true
+			}
+		}
+
+	case zapcore.StringerType:
+		if v, ok := f.Interface.(fmt.Stringer); ok {
+			if s := r.redactString(v.String()); s != v.String() {
+				return zap.String(f.Key, s), true
+			}
+		}
+
+	case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType, zapcore.ReflectType:
+		// redact a generic JSON representation of the value
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		if v, changed := r.redactValue(jsonValue(enc.Fields[f.Key])); changed {
+			return zap.Reflect(f.Key, v), true
+		}
+	}
+
+	return f, false
+}
+
+// jsonValue returns a generic JSON representation of v, or nil.
+func jsonValue(v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var out interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil
+	}
+	return out
+}
+
+// redactError returns err with redacted messages, if any message in its
+// chain has to change. The copy keeps the types and stacks of the chain.
+func (r *Redactor) redactError(err error) (error, bool) {
+	var chain []error
+	changed := false
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		chain = append(chain, e)
+		if s := r.redactString(e.Error()); s != e.Error() {
+			changed = true
+		}
+	}
+	if !changed {
+		return err, false
+	}
+
+	var out error
+	for i := len(chain) - 1; i >= 0; i-- {
+		e := chain[i]
+		out = &redactedError{
+			msg:    r.redactString(e.Error()),
+			typ:    errorType(e),
+			frames: ownStack(e),
+			cause:  out,
+		}
+	}
+	return out, true
+}
+
+// redactedError replaces an error of the chain of a redacted error.
+type redactedError struct {
+	msg    string
+	typ    string
+	frames []runtime.Frame
+	cause  error
+}
+
+func (e *redactedError) Error() string                { return e.msg }
+func (e *redactedError) ErrorType() string            { return e.typ }
+func (e *redactedError) StackFrames() []runtime.Frame { return e.frames }
+func (e *redactedError) Unwrap() error                { return e.cause }
+
+// redactedObject replaces a redacted inline object.
+type redactedObject map[string]interface{}
+
+func (o redactedObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	keys := make([]string, 0, len(o))
+	for k := range o {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var err error
+	for _, k := range keys {
+		err = multierr.Append(err, enc.AddReflected(k, o[k]))
+	}
+	return err
+}
+
+// redactValue redacts a value decoded from JSON.
+func (r *Redactor) redactValue(v interface{}) (interface{}, bool) {
+	changed := false
+
+	switch x := v.(type) {
+	case string:
+		s := r.redactString(x)
+		return s, s != x
+
+	case map[string]interface{}:
+		for k, e := range x {
+			if r.matchKey(k) {
+				x[k] = r.replace(fmt.Sprint(e))
+				changed = true
+			} else if e, c := r.redactValue(e); c {
+				x[k] = e
+				changed = true
+			}
+		}
+
+	case []interface{}:
+		for i, e := range x {
+			if e, c := r.redactValue(e); c {
+				x[i] = e
+				changed = true
+			}
+		}
+	}
+
+	return v, changed
+}
+
+func (r *Redactor) redactString(s string) string {
+	for _, re := range r.values {
+		s = re.ReplaceAllStringFunc(s, r.replace)
+	}
+	return s
+}
+
+func (r *Redactor) matchKey(key string) bool {
+	for _, re := range r.keys {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// replace returns the replacement for a redacted value.
+func (r *Redactor) replace(s string) string {
+	if !r.hash {
+		return redacted
+	}
+
+	h := hmac.New(sha256.New, r.hashKey)
+	h.Write([]byte(s))
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// fieldString returns the value of f as string, for hashing.
+func fieldString(f zapcore.Field) string {
+	if f.Type == zapcore.StringType {
+		return f.String
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	return fmt.Sprint(enc.Fields[f.Key])
+}
@@ -0,0 +1,134 @@
+package log
+
+import (
+	"fmt"
+	stdlog "log"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RedirectStdLog redirects output from the std/log package's global logger
+// to the default logger at info level, see RedirectStdLogAt.
+// It returns a func to restore the original prefix, flags and output.
+func RedirectStdLog() func() {
+	undo, err := RedirectStdLogAt(zap.InfoLevel)
+	if err != nil {
+		panic(err) // this should not happen, if it does, we need to fix it
+	}
+	return undo
+}
+
+// RedirectStdLogAt redirects output from the std/log package's global logger
+// to the default logger at the given level. Messages starting with a level hint,
+// like "ERROR:", "warn:" or "[debug]", are logged at that level instead.
+// Like zap.RedirectStdLogAt, levels above error are rejected, std/log
+// exits or panics on its own.
+// It returns a func to restore the original prefix, flags and output.
+func RedirectStdLogAt(level zapcore.Level) (func(), error) {
+	if level < zapcore.DebugLevel || level > zapcore.ErrorLevel {
+		return nil, fmt.Errorf("unrecognized level: %q", level)
+	}
+
+	flags := stdlog.Flags()
+	prefix := stdlog.Prefix()
+	out := stdlog.Writer()
+
+	// let zap take care of timestamps and callers
+	stdlog.SetFlags(0)
+	stdlog.SetPrefix("")
+	stdlog.SetOutput(&stdLogWriter{level: level, prefix: prefix})
+
+	return func() {
+		stdlog.SetFlags(flags)
+		stdlog.SetPrefix(prefix)
+		stdlog.SetOutput(out)
+	}, nil
+}
+
+// stdLogWriter is the io.Writer for std/log.
+// Each call to Write is one message.
+type stdLogWriter struct {
+	level  zapcore.Level
+	prefix string // original prefix
+}
+
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSuffix(string(p), "\n")
+
+	// in case flags or prefix have been set after redirecting
+	msg = strings.TrimPrefix(msg, w.prefix)
+	msg = stdLogHeaderRe.ReplaceAllString(msg, "")
+	msg = strings.TrimPrefix(msg, w.prefix) // std/log's Lmsgprefix
+
+	level, msg := parseLevelHint(msg, w.level)
+
+	if ce := defaultLogger().Check(level, msg); ce != nil {
+		if ce.Entry.Caller.Defined {
+			ce.Entry.Caller = stdLogCaller()
+		}
+		ce.Write()
+	}
+
+	return len(p), nil
+}
+
+// stdLogHeaderRe matches the header std/log writes for Ldate, Ltime,
+// Lmicroseconds, Lshortfile and Llongfile flags.
+var stdLogHeaderRe = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} )?(\d{2}:\d{2}:\d{2}(\.\d{6})? )?(\S+\.go:\d+: )?`)
+
+// levelHints maps lower case level hints to levels.
+var levelHints = map[string]zapcore.Level{
+	"debug":    zapcore.DebugLevel,
+	"info":     zapcore.InfoLevel,
+	"warn":     zapcore.WarnLevel,
+	"warning":  zapcore.WarnLevel,
+	"error":    zapcore.ErrorLevel,
+	"err":      zapcore.ErrorLevel,
+	"critical": zapcore.ErrorLevel, // std/log exits or panics on its own
+	"fatal":    zapcore.ErrorLevel,
+	"panic":    zapcore.ErrorLevel,
+}
+
+// levelHintRe matches "ERROR:", "ERROR ", "[error]" and "[ERROR]:"
+var levelHintRe = regexp.MustCompile(`^(?:\[([a-zA-Z]+)\]:?|([a-zA-Z]+):)\s*|^([A-Z]+)\s+`)
+
+// parseLevelHint returns the level of a hint at the beginning of msg
+// and the msg without the hint. If there is no hint, level is returned.
+func parseLevelHint(msg string, level zapcore.Level) (zapcore.Level, string) {
+	m := levelHintRe.FindStringSubmatch(msg)
+	if m == nil {
+		return level, msg
+	}
+
+	hint := m[1] + m[2] + m[3] // only one group matches
+	if l, ok := levelHints[strings.ToLower(hint)]; ok {
+		return l, msg[len(m[0]):]
+	}
+	return level, msg
+}
+
+// stdLogCaller returns the first caller outside of std/log and this file.
+func stdLogCaller() zapcore.EntryCaller {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs) // skip runtime.Callers, stdLogCaller and Write
+	frames := runtime.CallersFrames(pcs[:n])
+
+	for {
+		f, more := frames.Next()
+		if !isStdLogFrame(f.Function) {
+			return zapcore.NewEntryCaller(f.PC, f.File, f.Line, true)
+		}
+		if !more {
+			return zapcore.EntryCaller{}
+		}
+	}
+}
+
+func isStdLogFrame(function string) bool {
+	return strings.HasPrefix(function, "log.") ||
+		strings.HasPrefix(function, "log/slog.")
+}
@@ -0,0 +1,83 @@
+package log
+
+import (
+	stdlog "log"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRedirectStdLog(t *testing.T) {
+	obs := setTestLogger()
+
+	stdlog.SetPrefix("prefix: ")
+	defer stdlog.SetPrefix("")
+
+	undo := RedirectStdLog()
+	stdlog.Printf("hello %v", "world")
+	stdlog.Print("ERROR: oh no")
+	stdlog.SetFlags(stdlog.LstdFlags | stdlog.Lshortfile)
+	stdlog.Print("[warn] careful")
+	undo()
+
+	if stdlog.Prefix() != "prefix: " || stdlog.Flags() != stdlog.LstdFlags {
+		t.Errorf("expected prefix and flags to be restored")
+	}
+
+	logs := obs.TakeAll()
+	expect := []struct {
+		level zapcore.Level
+		msg   string
+	}{
+		{zapcore.InfoLevel, "hello world"},
+		{zapcore.ErrorLevel, "oh no"},
+		{zapcore.WarnLevel, "careful"},
+	}
+
+	if len(logs) != len(expect) {
+		t.Fatalf("expected %v logs, got %v", len(expect), len(logs))
+	}
+
+	for i, l := range logs {
+		if l.Level != expect[i].level || l.Message != expect[i].msg {
+			t.Errorf("expected %v %q, got %v %q", expect[i].level, expect[i].msg, l.Level, l.Message)
+		}
+		if filepath.Base(l.Caller.File) != "redirect_test.go" {
+			t.Errorf("expected caller in redirect_test.go, got %v", l.Caller.File)
+		}
+	}
+}
+
+func TestRedirectStdLogAtLevels(t *testing.T) {
+	for _, l := range []zapcore.Level{zapcore.DPanicLevel, zapcore.PanicLevel, zapcore.FatalLevel} {
+		if undo, err := RedirectStdLogAt(l); err == nil {
+			undo()
+			t.Errorf("%v: expected error", l)
+		}
+	}
+}
+
+func TestParseLevelHint(t *testing.T) {
+	tt := []struct {
+		in    string
+		level zapcore.Level
+		msg   string
+	}{
+		{"hello", zapcore.InfoLevel, "hello"},
+		{"ERROR: hello", zapcore.ErrorLevel, "hello"},
+		{"error:hello", zapcore.ErrorLevel, "hello"},
+		{"WARN hello", zapcore.WarnLevel, "hello"},
+		{"[debug] hello", zapcore.DebugLevel, "hello"},
+		{"[WARNING]: hello", zapcore.WarnLevel, "hello"},
+		{"Listening: :8080", zapcore.InfoLevel, "Listening: :8080"},
+		{"errors happen", zapcore.InfoLevel, "errors happen"},
+	}
+
+	for _, v := range tt {
+		level, msg := parseLevelHint(v.in, zapcore.InfoLevel)
+		if level != v.level || msg != v.msg {
+			t.Errorf("%q: expected %v %q, got %v %q", v.in, v.level, v.msg, level, msg)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package log
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+// RouterMode decides how many routes an entry takes.
+type RouterMode string
+
+const (
+	// RouteFirstMatch sends an entry to the cores of the first matching route.
+	RouteFirstMatch RouterMode = "first"
+
+	// RouteAll sends an entry to the cores of all matching routes.
+	RouteAll RouterMode = "all"
+)
+
+// Route sends matching entries to its cores. Empty conditions match all entries.
+type Route struct {
+	// Level matches entries enabled at this level.
+	Level zapcore.LevelEnabler
+
+	// Logger matches logger names with this prefix, i.e. "billing"
+	// matches "billing" and "billing.invoices", but not "billingx".
+	Logger string
+
+	// Message is a regular expression matched against the message.
+	Message string
+
+	// Fields match entries with fields of these keys, including context
+	// fields. Values are compared by their string representation,
+	// a nil value matches any value.
+	Fields map[string]interface{}
+
+	Cores []zapcore.Core
+}
+
+type RouterConfig struct {
+	Mode   RouterMode
+	Routes []Route
+
+	// Default cores receive entries that match no route.
+	Default []zapcore.Core
+}
+
+func NewRouterConfig() RouterConfig {
+	return RouterConfig{
+		Mode: RouteFirstMatch,
+	}
+}
+
+// Build returns a Router for the configured routes.
+func (cfg RouterConfig) Build() (*Router, error) {
+	if cfg.Mode != RouteFirstMatch && cfg.Mode != RouteAll {
+		return nil, fmt.Errorf("invalid Mode %q", cfg.Mode)
+	}
+
+	r := &Router{mode: cfg.Mode, keys: make(map[string]bool)}
+
+	for i, route := range cfg.Routes {
+		rr := routerRoute{
+			level:  route.Level,
+			logger: route.Logger,
+			fields: make(map[string]string),
+		}
+
+		if route.Message != "" {
+			re, err := regexp.Compile(route.Message)
+			if err != nil {
+				return nil, fmt.Errorf("route %v: invalid Message: %v", i, err)
+			}
+			rr.message = re
+		}
+
+		for k, v := range route.Fields {
+			if v == nil {
+				rr.present = append(rr.present, k)
+			} else {
+				rr.fields[k] = fmt.Sprint(v)
+			}
+			r.keys[k] = true
+			r.deferred = true
+		}
+
+		for _, c := range route.Cores {
+			rr.cores = append(rr.cores, r.add(c))
+		}
+		r.routes = append(r.routes, rr)
+	}
+
+	for _, c := range cfg.Default {
+		r.defaults = append(r.defaults, r.add(c))
+	}
+
+	return r, nil
+}
+
+// Router is a core that sends entries to other cores depending on
+// their level, logger name, message or fields, unlike zapcore.NewTee,
+// which sends every entry to every core.
+//
+//	c := log.NewRouterConfig()
+//	c.Routes = []log.Route{
+//		{Level: zapcore.ErrorLevel, Fields: map[string]interface{}{"component": "billing"}, Cores: []zapcore.Core{billingSlack}},
+//		{Logger: "audit", Cores: []zapcore.Core{stackdriver}},
+//	}
+//	c.Default = []zapcore.Core{console, stackdriver}
+//	router, err := c.Build()
+//
+// Routes without field conditions are resolved when an entry is checked,
+// field conditions can only be evaluated when it's written. Then the cores of
+// routes that might match are asked in Check and the entry is written to those
+// that accepted it, once their route matches.
+// Each core receives an entry at most once, even if it's part of multiple
+// matching routes, and checks it as it would without a Router, so a tee of
+// cores with their own levels only writes to the ones that enable the entry.
+type Router struct {
+	mode     RouterMode
+	routes   []routerRoute
+	defaults []int          // indexes into cores
+	cores    []zapcore.Core // unique cores of all routes and defaults
+	keys     map[string]bool
+	deferred bool // some routes match on fields
+
+	context map[string]interface{} // context fields with keys in keys
+}
+
+type routerRoute struct {
+	level   zapcore.LevelEnabler
+	logger  string
+	message *regexp.Regexp
+	fields  map[string]string
+	present []string
+	cores   []int // indexes into Router.cores
+}
+
+// add adds core to r.cores, unless it's already there, and returns its index.
+func (r *Router) add(core zapcore.Core) int {
+	for i, c := range r.cores {
+		if sameCore(c, core) {
+			return i
+		}
+	}
+	r.cores = append(r.cores, core)
+	return len(r.cores) - 1
+}
+
+func sameCore(a, b zapcore.Core) bool {
+	return reflect.TypeOf(a) == reflect.TypeOf(b) && reflect.TypeOf(a).Comparable() && a == b
+}
+
+func (r *Router) Enabled(level zapcore.Level) bool {
+	for _, c := range r.cores {
+		if c.Enabled(level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Router) With(fields []zapcore.Field) zapcore.Core {
+	clone := &Router{
+		mode:     r.mode,
+		routes:   r.routes,
+		defaults: r.defaults,
+		cores:    make([]zapcore.Core, len(r.cores)),
+		keys:     r.keys,
+		deferred: r.deferred,
+	}
+
+	for i, c := range r.cores {
+		clone.cores[i] = c.With(fields)
+	}
+
+	if r.deferred {
+		clone.context = r.encode(r.context, fields)
+	}
+
+	return clone
+}
+
+func (r *Router) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if r.deferred {
+		// fields are only known in Write, ask the cores the entry might be
+		// routed to once, so stateful cores like Sampler only count it once
+		var checked []*zapcore.CheckedEntry
+		for _, i := range r.candidates(entry) {
+			ce := r.cores[i].Check(entry, nil)
+			if ce == nil {
+				continue
+			}
+			if checked == nil {
+				checked = make([]*zapcore.CheckedEntry, len(r.cores))
+			}
+			checked[i] = ce
+		}
+		if checked == nil {
+			return checkedEntry
+		}
+		return checkedEntry.AddCore(entry, &routedEntry{Router: r, checked: checked})
+	}
+
+	for _, i := range r.match(entry, nil) {
+		checkedEntry = r.cores[i].Check(entry, checkedEntry)
+	}
+	return checkedEntry
+}
+
+// Write is only called, if routes match on fields.
+func (r *Router) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	var err error
+	for _, i := range r.match(entry, r.encode(r.context, fields)) {
+		if ce := r.cores[i].Check(entry, nil); ce != nil {
+			err = multierr.Append(err, writeChecked(ce, entry, fields))
+		}
+	}
+	return err
+}
+
+// routedEntry writes an entry checked by a Router with field conditions
+// to the matching cores, that accepted it in Check.
+type routedEntry struct {
+	*Router
+	checked []*zapcore.CheckedEntry // indexes into Router.cores
+}
+
+func (e *routedEntry) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	var err error
+	for _, i := range e.match(entry, e.encode(e.context, fields)) {
+		if ce := e.checked[i]; ce != nil {
+			err = multierr.Append(err, writeChecked(ce, entry, fields))
+		}
+	}
+	return err
+}
+
+func (r *Router) Sync() error {
+	var err error
+	for _, c := range r.cores {
+		err = multierr.Append(err, c.Sync())
+	}
+	return err
+}
+
+// candidates returns the indexes of the cores that might receive entry,
+// before its fields are known.
+func (r *Router) candidates(entry zapcore.Entry) []int {
+	var cores []int
+	seen := make([]bool, len(r.cores))
+	add := func(route []int) {
+		for _, i := range route {
+			if !seen[i] {
+				seen[i] = true
+				cores = append(cores, i)
+			}
+		}
+	}
+
+	found := false // a route matches for sure
+	for _, route := range r.routes {
+		if !route.matchesEntry(entry) {
+			continue
+		}
+		add(route.cores)
+		if len(route.fields) == 0 && len(route.present) == 0 {
+			found = true
+			if r.mode == RouteFirstMatch {
+				break
+			}
+		}
+	}
+
+	if !found {
+		add(r.defaults)
+	}
+	return cores
+}
+
+// match returns the indexes of the cores that receive entry.
+func (r *Router) match(entry zapcore.Entry, fields map[string]interface{}) []int {
+	var matched []int
+	seen := make([]bool, len(r.cores))
+	add := func(cores []int) {
+		for _, i := range cores {
+			if !seen[i] {
+				seen[i] = true
+				matched = append(matched, i)
+			}
+		}
+	}
+
+	found := false
+	for _, route := range r.routes {
+		if !route.matches(entry, fields) {
+			continue
+		}
+		found = true
+		add(route.cores)
+		if r.mode == RouteFirstMatch {
+			break
+		}
+	}
+
+	if !found {
+		add(r.defaults)
+	}
+	return matched
+}
+
+func (rr *routerRoute) matches(entry zapcore.Entry, fields map[string]interface{}) bool {
+	if !rr.matchesEntry(entry) {
+		return false
+	}
+
+	for _, k := range rr.present {
+		if _, ok := fields[k]; !ok {
+			return false
+		}
+	}
+
+	for k, v := range rr.fields {
+		fv, ok := fields[k]
+		if !ok || fmt.Sprint(fv) != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchesEntry reports if the conditions besides fields match entry.
+func (rr *routerRoute) matchesEntry(entry zapcore.Entry) bool {
+	if rr.level != nil && !rr.level.Enabled(entry.Level) {
+		return false
+	}
+
+	if rr.logger != "" && entry.LoggerName != rr.logger && !strings.HasPrefix(entry.LoggerName, rr.logger+".") {
+		return false
+	}
+
+	if rr.message != nil && !rr.message.MatchString(entry.Message) {
+		return false
+	}
+
+	return true
+}
+
+// encode returns a copy of context with the values of fields
+// that routes match on.
+func (r *Router) encode(context map[string]interface{}, fields []zapcore.Field) map[string]interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	for k, v := range context {
+		enc.Fields[k] = v
+	}
+	for _, f := range fields {
+		if r.keys[f.Key] {
+			f.AddTo(enc)
+		}
+	}
+	return enc.Fields
+}
@@ -0,0 +1,189 @@
+package log
+
+import (
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRouter(t *testing.T) {
+	billing, billingObs := observer.New(zapcore.DebugLevel)
+	audit, auditObs := observer.New(zapcore.DebugLevel)
+	def, defObs := observer.New(zapcore.InfoLevel)
+
+	c := NewRouterConfig()
+	c.Routes = []Route{
+		{Level: zapcore.ErrorLevel, Fields: map[string]interface{}{"component": "billing"}, Cores: []zapcore.Core{billing}},
+		{Logger: "audit", Cores: []zapcore.Core{audit}},
+		{Message: "^payment", Fields: map[string]interface{}{"amount": nil}, Cores: []zapcore.Core{billing, def}},
+	}
+	c.Default = []zapcore.Core{def}
+	r, err := c.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.New(r)
+
+	component := logger.With(zap.String("component", "billing"))
+	component.Error("charge failed")
+	component.Info("charged")
+	logger.Error("charge failed", zap.String("component", "billing"))
+	logger.Error("charge failed", zap.String("component", "shipping"))
+
+	logger.Named("audit").Info("login")
+	logger.Named("audit.admin").Info("delete user")
+	logger.Named("auditx").Info("no audit")
+	logger.Named("audit").Debug("debug audit")
+
+	logger.Info("payment received", zap.Int("amount", 10))
+	logger.Info("payment received")
+	logger.Debug("not enabled")
+
+	if msgs, expect := messages(billingObs), []string{"charge failed", "charge failed", "payment received"}; !reflect.DeepEqual(msgs, expect) {
+		t.Errorf("billing: expected %v, got %v", expect, msgs)
+	}
+	if msgs, expect := messages(auditObs), []string{"login", "delete user", "debug audit"}; !reflect.DeepEqual(msgs, expect) {
+		t.Errorf("audit: expected %v, got %v", expect, msgs)
+	}
+	if msgs, expect := messages(defObs), []string{"charged", "charge failed", "no audit", "payment received", "payment received"}; !reflect.DeepEqual(msgs, expect) {
+		t.Errorf("default: expected %v, got %v", expect, msgs)
+	}
+}
+
+func TestRouterChecksOnce(t *testing.T) {
+	core, obs := observer.New(zapcore.DebugLevel)
+
+	sc := NewSamplerConfig()
+	sc.Policy = SamplingPolicy{Initial: 2}
+	sampler, err := sc.Build(core)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewRouterConfig()
+	c.Routes = []Route{
+		{Fields: map[string]interface{}{"component": "billing"}, Cores: []zapcore.Core{sampler}},
+	}
+	r, err := c.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.New(r).With(zap.String("component", "billing"))
+
+	for i := 0; i < 3; i++ {
+		logger.Info("charged")
+	}
+
+	if msgs, expect := messages(obs), []string{"charged", "charged"}; !reflect.DeepEqual(msgs, expect) {
+		t.Errorf("expected %v, got %v", expect, msgs)
+	}
+}
+
+func TestRouterChecksCandidates(t *testing.T) {
+	core, obs := observer.New(zapcore.DebugLevel)
+	def, defObs := observer.New(zapcore.DebugLevel)
+
+	sc := NewSamplerConfig()
+	sc.Policy = SamplingPolicy{Initial: 1}
+	sampler, err := sc.Build(core)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewRouterConfig()
+	c.Routes = []Route{
+		{Logger: "billing", Fields: map[string]interface{}{"component": "billing"}, Cores: []zapcore.Core{sampler}},
+	}
+	c.Default = []zapcore.Core{def}
+	r, err := c.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.New(r).With(zap.String("component", "billing"))
+
+	logger.Named("shipping").Info("charged") // the route can't match, sampler isn't asked
+	logger.Named("billing").Info("charged")
+
+	if msgs, expect := messages(obs), []string{"charged"}; !reflect.DeepEqual(msgs, expect) {
+		t.Errorf("expected %v, got %v", expect, msgs)
+	}
+	if msgs, expect := messages(defObs), []string{"charged"}; !reflect.DeepEqual(msgs, expect) {
+		t.Errorf("default: expected %v, got %v", expect, msgs)
+	}
+}
+
+func TestRouterTee(t *testing.T) {
+	debug, debugObs := observer.New(zapcore.DebugLevel)
+	errs, errsObs := observer.New(zapcore.ErrorLevel)
+
+	c := NewRouterConfig()
+	c.Routes = []Route{
+		{Fields: map[string]interface{}{"component": "billing"}, Cores: []zapcore.Core{zapcore.NewTee(debug, errs)}},
+	}
+	r, err := c.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.New(r)
+	logger.Info("charged", zap.String("component", "billing"))
+	logger.Error("charge failed", zap.String("component", "billing"))
+
+	if msgs, expect := messages(debugObs), []string{"charged", "charge failed"}; !reflect.DeepEqual(msgs, expect) {
+		t.Errorf("debug: expected %v, got %v", expect, msgs)
+	}
+	if msgs, expect := messages(errsObs), []string{"charge failed"}; !reflect.DeepEqual(msgs, expect) {
+		t.Errorf("errors: expected %v, got %v", expect, msgs)
+	}
+}
+
+func TestRouterModes(t *testing.T) {
+	for _, mode := range []RouterMode{RouteFirstMatch, RouteAll} {
+		t.Run(string(mode), func(t *testing.T) {
+			errs, errsObs := observer.New(zapcore.DebugLevel)
+			all, allObs := observer.New(zapcore.DebugLevel)
+
+			c := NewRouterConfig()
+			c.Mode = mode
+			c.Routes = []Route{
+				{Level: zapcore.ErrorLevel, Cores: []zapcore.Core{errs}},
+				{Cores: []zapcore.Core{all, errs}},
+			}
+			r, err := c.Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			logger := zap.New(r)
+			logger.Error("error")
+			logger.Info("info")
+
+			expectErrs := []string{"error", "info"} // a core receives an entry once
+			expectAll := []string{"info"}
+			if mode == RouteAll {
+				expectAll = []string{"error", "info"}
+			}
+			if msgs := messages(errsObs); !reflect.DeepEqual(msgs, expectErrs) {
+				t.Errorf("expected %v, got %v", expectErrs, msgs)
+			}
+			if msgs := messages(allObs); !reflect.DeepEqual(msgs, expectAll) {
+				t.Errorf("expected %v, got %v", expectAll, msgs)
+			}
+		})
+	}
+}
+
+func TestRouterConfigBuild(t *testing.T) {
+	c := NewRouterConfig()
+	c.Mode = "random"
+	if _, err := c.Build(); err == nil {
+		t.Error("expected error for invalid mode")
+	}
+
+	c = NewRouterConfig()
+	c.Routes = []Route{{Message: "("}}
+	if _, err := c.Build(); err == nil {
+		t.Error("expected error for invalid message regexp")
+	}
+}
@@ -0,0 +1,389 @@
+package log
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SamplingPolicy logs the first Initial entries per tick and
+// every Thereafter entry after that. Zero Thereafter drops the rest.
+type SamplingPolicy struct {
+	Initial    int `json:"initial" yaml:"initial"`
+	Thereafter int `json:"thereafter" yaml:"thereafter"`
+}
+
+type SamplerConfig struct {
+	// Tick is the interval the policies apply to.
+	Tick time.Duration
+
+	// Policy is the default sampling policy.
+	Policy SamplingPolicy
+
+	// Levels overrides the default policy for some levels.
+	Levels map[zapcore.Level]SamplingPolicy
+
+	// Unsampled entries are never sampled, by default errors and above.
+	Unsampled zapcore.LevelEnabler
+
+	// KeyByCaller samples entries with the same message and logger name
+	// separately for each caller. The caller is looked up for every entry,
+	// which makes logging slower.
+	KeyByCaller bool
+
+	// Summary logs how many entries were dropped for a message, once per tick,
+	// when the tick is over, and when the core is synced.
+	Summary bool
+
+	// MaxKeys limits the number of sampled messages that are kept track of.
+	// Entries with new messages aren't sampled, once the limit is reached,
+	// until messages without recent entries are forgotten at the next tick.
+	MaxKeys int
+}
+
+func NewSamplerConfig() SamplerConfig {
+	return SamplerConfig{
+		Tick:      time.Second,
+		Policy:    SamplingPolicy{Initial: 100, Thereafter: 100},
+		Unsampled: zap.NewAtomicLevelAt(zap.ErrorLevel),
+		Summary:   true,
+		MaxKeys:   4096,
+	}
+}
+
+// Build wraps core with a Sampler.
+func (cfg SamplerConfig) Build(core zapcore.Core) (*Sampler, error) {
+	if cfg.Tick <= 0 {
+		return nil, fmt.Errorf("invalid Tick")
+	}
+	if cfg.MaxKeys <= 0 {
+		return nil, fmt.Errorf("invalid MaxKeys")
+	}
+
+	return &Sampler{
+		Core: core,
+		cfg:  cfg,
+		state: &samplerState{
+			root:     core,
+			counters: make(map[samplerKey]*samplerCounter),
+			totals:   make(map[zapcore.Level]SamplingTotals),
+		},
+	}, nil
+}
+
+// Sampler is a core that samples entries by level, logger name and message,
+// and optionally caller. Unlike zap's sampler, it keeps track of what it dropped.
+//
+//	c := log.NewSamplerConfig()
+//	c.Levels = map[zapcore.Level]log.SamplingPolicy{
+//		zapcore.DebugLevel: {Initial: 10, Thereafter: 0},
+//	}
+//	s, err := c.Build(core)
+//	logger := zap.New(s)
+type Sampler struct {
+	zapcore.Core
+
+	cfg   SamplerConfig
+	state *samplerState // shared with clones
+}
+
+// SamplingStats are the counts of a sampled message since it's kept track of.
+// Messages without recent entries are forgotten once MaxKeys is reached,
+// see Sampler.Totals for counts that are kept.
+type SamplingStats struct {
+	Level   zapcore.Level `json:"level"`
+	Logger  string        `json:"logger,omitempty"`
+	Message string        `json:"message"`
+	Caller  string        `json:"caller,omitempty"`
+	Logged  int64         `json:"logged"`
+	Dropped int64         `json:"dropped"`
+}
+
+// SamplingTotals are the counts of a level since the Sampler was built.
+type SamplingTotals struct {
+	Logged  int64 `json:"logged"`
+	Dropped int64 `json:"dropped"`
+}
+
+type samplerKey struct {
+	level   zapcore.Level
+	logger  string
+	message string
+	caller  string
+}
+
+type samplerCounter struct {
+	start   time.Time // of the current tick
+	n       int       // entries in the current tick
+	pending int64     // dropped entries not summarized yet
+	logged  int64
+	dropped int64
+}
+
+type samplerState struct {
+	root zapcore.Core // for summaries, without context fields
+
+	mu       sync.Mutex
+	counters map[samplerKey]*samplerCounter
+	totals   map[zapcore.Level]SamplingTotals // not pruned
+	timer    *time.Timer                      // of the next sweep, if scheduled
+}
+
+// summary is a summary entry to be written after unlocking samplerState
+type summary struct {
+	key     samplerKey
+	dropped int64
+	at      time.Time
+}
+
+func (s *Sampler) With(fields []zapcore.Field) zapcore.Core {
+	return &Sampler{Core: s.Core.With(fields), cfg: s.cfg, state: s.state}
+}
+
+func (s *Sampler) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !s.Core.Enabled(entry.Level) {
+		return checkedEntry
+	}
+	if s.cfg.Unsampled != nil && s.cfg.Unsampled.Enabled(entry.Level) {
+		return s.Core.Check(entry, checkedEntry)
+	}
+
+	policy, ok := s.cfg.Levels[entry.Level]
+	if !ok {
+		policy = s.cfg.Policy
+	}
+
+	key := samplerKey{level: entry.Level, logger: entry.LoggerName, message: entry.Message}
+	if s.cfg.KeyByCaller {
+		key.caller = samplerCaller() // entry.Caller isn't set yet
+	}
+
+	logged, sum := s.state.count(key, policy, entry.Time, s.cfg)
+	if sum != nil {
+		s.state.writeSummary(*sum)
+	}
+	if !logged {
+		return checkedEntry
+	}
+	return s.Core.Check(entry, checkedEntry)
+}
+
+// Sync logs the summaries of all dropped entries and syncs the wrapped core.
+func (s *Sampler) Sync() error {
+	if s.cfg.Summary {
+		for _, sum := range s.state.takeSummaries(time.Now()) {
+			s.state.writeSummary(sum)
+		}
+	}
+	return s.Core.Sync()
+}
+
+// Stats returns the counts of all sampled messages, most dropped first.
+func (s *Sampler) Stats() []SamplingStats {
+	s.state.mu.Lock()
+	stats := make([]SamplingStats, 0, len(s.state.counters))
+	for k, c := range s.state.counters {
+		stats = append(stats, SamplingStats{
+			Level:   k.level,
+			Logger:  k.logger,
+			Message: k.message,
+			Caller:  k.caller,
+			Logged:  c.logged,
+			Dropped: c.dropped,
+		})
+	}
+	s.state.mu.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Dropped != stats[j].Dropped {
+			return stats[i].Dropped > stats[j].Dropped
+		}
+		return stats[i].Message < stats[j].Message
+	})
+	return stats
+}
+
+// Totals returns the logged and dropped counts per sampled level, including
+// messages that Stats doesn't return anymore.
+func (s *Sampler) Totals() map[zapcore.Level]SamplingTotals {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	totals := make(map[zapcore.Level]SamplingTotals, len(s.state.totals))
+	for l, t := range s.state.totals {
+		totals[l] = t
+	}
+	return totals
+}
+
+func (st *samplerState) writeSummary(sum summary) {
+	entry := zapcore.Entry{
+		Level:      sum.key.level,
+		Time:       sum.at,
+		LoggerName: sum.key.logger,
+		Message:    fmt.Sprintf("Dropped %d entries for message %q", sum.dropped, sum.key.message),
+	}
+
+	fields := []zapcore.Field{
+		zap.Int64("dropped", sum.dropped),
+		zap.String("sampledMessage", sum.key.message),
+	}
+	if sum.key.caller != "" {
+		fields = append(fields, zap.String("sampledCaller", sum.key.caller))
+	}
+
+	if ce := st.root.Check(entry, nil); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+// count counts an entry and reports if it should be logged. If the tick of
+// key is over and entries were dropped, it returns their summary.
+func (st *samplerState) count(key samplerKey, policy SamplingPolicy, now time.Time, cfg SamplerConfig) (bool, *summary) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	total := st.totals[key.level]
+	defer func() { st.totals[key.level] = total }()
+
+	c, ok := st.counters[key]
+	if !ok {
+		if len(st.counters) >= cfg.MaxKeys {
+			st.schedule(cfg) // to prune
+			total.Logged++
+			return true, nil // don't sample, if we can't keep track
+		}
+		c = &samplerCounter{start: now}
+		st.counters[key] = c
+	}
+
+	var sum *summary
+	if now.Sub(c.start) >= cfg.Tick {
+		if cfg.Summary && c.pending > 0 {
+			sum = &summary{key: key, dropped: c.pending, at: now}
+		}
+		c.start = now
+		c.n = 0
+		c.pending = 0
+	}
+
+	c.n++
+	if c.n <= policy.Initial || (policy.Thereafter > 0 && (c.n-policy.Initial)%policy.Thereafter == 0) {
+		c.logged++
+		total.Logged++
+		return true, sum
+	}
+
+	c.dropped++
+	total.Dropped++
+	if cfg.Summary {
+		c.pending++
+		st.schedule(cfg)
+	}
+	return false, sum
+}
+
+// schedule makes sure a sweep runs after the next tick. A timer is only
+// running while there are summaries to write or counters to prune.
+// st.mu must be held.
+func (st *samplerState) schedule(cfg SamplerConfig) {
+	if st.timer == nil {
+		st.timer = time.AfterFunc(cfg.Tick, func() { st.sweep(cfg) })
+	}
+}
+
+// sweep writes the summaries of keys whose tick is over and prunes
+// counters, if there are too many.
+func (st *samplerState) sweep(cfg SamplerConfig) {
+	now := time.Now()
+
+	st.mu.Lock()
+	st.timer = nil
+
+	var sums []summary
+	for k, c := range st.counters {
+		if c.pending == 0 {
+			continue
+		}
+		if now.Sub(c.start) < cfg.Tick {
+			st.schedule(cfg) // not yet
+			continue
+		}
+		sums = append(sums, summary{key: k, dropped: c.pending, at: now})
+		c.pending = 0
+	}
+
+	if len(st.counters) >= cfg.MaxKeys {
+		st.prune(now, cfg.Tick)
+	}
+	st.mu.Unlock()
+
+	sortSummaries(sums)
+	for _, sum := range sums {
+		st.writeSummary(sum)
+	}
+}
+
+// prune removes counters whose tick is over and which have nothing to
+// summarize. It's called at most once per tick. st.mu must be held.
+func (st *samplerState) prune(now time.Time, tick time.Duration) {
+	for k, c := range st.counters {
+		if c.pending == 0 && now.Sub(c.start) >= tick {
+			delete(st.counters, k)
+		}
+	}
+}
+
+// takeSummaries returns and resets the dropped entries of all keys.
+func (st *samplerState) takeSummaries(now time.Time) []summary {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var sums []summary
+	for k, c := range st.counters {
+		if c.pending > 0 {
+			sums = append(sums, summary{key: k, dropped: c.pending, at: now})
+			c.pending = 0
+		}
+	}
+
+	sortSummaries(sums)
+	return sums
+}
+
+func sortSummaries(sums []summary) {
+	sort.Slice(sums, func(i, j int) bool {
+		return sums[i].key.message < sums[j].key.message
+	})
+}
+
+// samplerCaller returns the first caller outside of zap and this package.
+func samplerCaller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs) // skip runtime.Callers, samplerCaller and Check
+	frames := runtime.CallersFrames(pcs[:n])
+
+	for {
+		f, more := frames.Next()
+		if !isLoggingFrame(f) {
+			return zapcore.NewEntryCaller(f.PC, f.File, f.Line, true).TrimmedPath()
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+func isLoggingFrame(f runtime.Frame) bool {
+	if strings.HasPrefix(f.Function, "go.uber.org/zap") {
+		return true
+	}
+	// funcs of this package, but not its tests
+	return strings.HasPrefix(f.Function, "github.com/mattes/log.") && !strings.HasSuffix(f.File, "_test.go")
+}
@@ -0,0 +1,152 @@
+package log
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSampler(t *testing.T) {
+	core, obs := observer.New(zapcore.DebugLevel)
+
+	c := NewSamplerConfig()
+	c.Tick = time.Hour
+	c.Policy = SamplingPolicy{Initial: 2, Thereafter: 3}
+	c.Levels = map[zapcore.Level]SamplingPolicy{zapcore.DebugLevel: {Initial: 1}}
+	s, err := c.Build(core)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.New(s)
+
+	for i := 0; i < 6; i++ {
+		logger.Info("a") // 1, 2 and 5 are logged
+		logger.Debug("b")
+		logger.Error("c") // never sampled
+	}
+	logger.Named("other").Info("a")
+
+	expect := []string{"a", "b", "c", "a", "c", "c", "c", "a", "c", "c", "a"}
+	if msgs := messages(obs); !reflect.DeepEqual(msgs, expect) {
+		t.Errorf("expected %v, got %v", expect, msgs)
+	}
+
+	stats := s.Stats()
+	if len(stats) != 3 || stats[0].Message != "b" || stats[0].Dropped != 5 || stats[1].Message != "a" || stats[1].Logged != 3 || stats[1].Dropped != 3 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// summaries on sync
+	logger.Sync()
+	logs := obs.TakeAll()
+	if len(logs) != 2 {
+		t.Fatalf("expected two summaries, got %v", len(logs))
+	}
+	if logs[0].Message != `Dropped 3 entries for message "a"` || logs[0].Level != zapcore.InfoLevel || logs[0].ContextMap()["dropped"] != int64(3) {
+		t.Errorf("unexpected summary %v %v", logs[0].Message, logs[0].ContextMap())
+	}
+	if logs[1].Message != `Dropped 5 entries for message "b"` || logs[1].Level != zapcore.DebugLevel {
+		t.Errorf("unexpected summary %v", logs[1].Message)
+	}
+}
+
+func TestSamplerSummaryAfterTick(t *testing.T) {
+	core, obs := observer.New(zapcore.DebugLevel)
+
+	c := NewSamplerConfig()
+	c.Tick = 10 * time.Millisecond
+	c.Policy = SamplingPolicy{Initial: 1}
+	c.KeyByCaller = true
+	s, err := c.Build(core)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.New(s)
+
+	for i := 0; i < 3; i++ {
+		if i == 2 {
+			time.Sleep(20 * time.Millisecond) // next tick
+		}
+		logger.Info("a") // if line changes, update test below
+	}
+	logger.Info("a") // different caller
+
+	expect := []string{"a", `Dropped 1 entries for message "a"`, "a", "a"}
+	logs := obs.TakeAll()
+	msgs := make([]string, 0)
+	for _, l := range logs {
+		msgs = append(msgs, l.Message)
+	}
+	if !reflect.DeepEqual(msgs, expect) {
+		t.Fatalf("expected %v, got %v", expect, msgs)
+	}
+	if caller, _ := logs[1].ContextMap()["sampledCaller"].(string); !strings.HasSuffix(caller, "/sampler_test.go:75") {
+		t.Errorf("unexpected caller %v", caller)
+	}
+}
+
+func TestSamplerPeriodicSummary(t *testing.T) {
+	core, obs := observer.New(zapcore.DebugLevel)
+
+	c := NewSamplerConfig()
+	c.Tick = 10 * time.Millisecond
+	c.Policy = SamplingPolicy{Initial: 1}
+	s, err := c.Build(core)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.New(s)
+
+	for i := 0; i < 3; i++ {
+		logger.Info("a")
+	}
+
+	// written without logging "a" again or syncing
+	time.Sleep(50 * time.Millisecond)
+	expect := []string{"a", `Dropped 2 entries for message "a"`}
+	if msgs := messages(obs); !reflect.DeepEqual(msgs, expect) {
+		t.Errorf("expected %v, got %v", expect, msgs)
+	}
+}
+
+func TestSamplerMaxKeys(t *testing.T) {
+	core, obs := observer.New(zapcore.DebugLevel)
+
+	c := NewSamplerConfig()
+	c.Tick = 10 * time.Millisecond
+	c.Policy = SamplingPolicy{Initial: 1}
+	c.Summary = false
+	c.MaxKeys = 1
+	s, err := c.Build(core)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.New(s)
+
+	logger.Info("a")
+	logger.Info("a") // dropped
+	logger.Info("b") // not sampled, "a" is kept track of
+	logger.Info("b")
+
+	// "a" is forgotten at the next tick
+	time.Sleep(50 * time.Millisecond)
+	logger.Info("b")
+	logger.Info("b") // dropped
+
+	expect := []string{"a", "b", "b", "b"}
+	if msgs := messages(obs); !reflect.DeepEqual(msgs, expect) {
+		t.Errorf("expected %v, got %v", expect, msgs)
+	}
+	// totals keep the counts of "a"
+	if stats := s.Stats(); len(stats) != 1 || stats[0].Message != "b" {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if totals := s.Totals(); totals[zapcore.InfoLevel] != (SamplingTotals{Logged: 4, Dropped: 2}) {
+		t.Errorf("unexpected totals %+v", totals)
+	}
+}
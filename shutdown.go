@@ -0,0 +1,142 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+// closer is implemented by cores that hold resources, like clients of
+// remote services, i.e. the googleStackdriver and googleErrorReporting cores.
+type closer interface {
+	Close() error
+}
+
+// Shutdown flushes all cores registered with NamedCore and closes the ones
+// implementing Close() error. Once they are closed, it syncs the default
+// logger, to flush unnamed cores. Named cores are shut down concurrently,
+// everything is given up when ctx is done. The returned error contains the
+// failures of each core, prefixed by its name.
+//
+// Closed cores aren't synced or closed again, so calling Shutdown more than
+// once is safe. Logs after Shutdown might be lost.
+func Shutdown(ctx context.Context) error {
+	namedCoresMu.RLock()
+	cores := make([]*namedCoreStats, len(namedCores))
+	copy(cores, namedCores)
+	namedCoresMu.RUnlock()
+
+	type result struct {
+		i   int
+		err error
+	}
+	results := make(chan result, len(cores)) // buffered, so late results don't block
+
+	for i, s := range cores {
+		go func(i int, c *namedCore) {
+			results <- result{i, multierr.Append(c.Sync(), c.Close())}
+		}(i, s.core)
+	}
+
+	var err error
+	done := make([]bool, len(cores))
+	for pending := len(done); pending > 0; pending-- {
+		select {
+		case r := <-results:
+			done[r.i] = true
+			if r.err != nil {
+				err = multierr.Append(err, fmt.Errorf("%v: %w", cores[r.i].name, r.err))
+			}
+
+		case <-ctx.Done():
+			for i := range done {
+				if !done[i] {
+					err = multierr.Append(err, fmt.Errorf("%v: %w", cores[i].name, ctx.Err()))
+				}
+			}
+			return multierr.Append(err, fmt.Errorf("default logger: %w", ctx.Err()))
+		}
+	}
+
+	synced := make(chan error, 1)
+	go func() {
+		synced <- ignoreSyncErrors(defaultLogger().Sync())
+	}()
+
+	select {
+	case e := <-synced:
+		if e != nil {
+			err = multierr.Append(err, fmt.Errorf("default logger: %w", e))
+		}
+	case <-ctx.Done():
+		err = multierr.Append(err, fmt.Errorf("default logger: %w", ctx.Err()))
+	}
+	return err
+}
+
+// ignoreSyncErrors drops the errors returned when syncing stdout or stderr
+// attached to a terminal, see https://github.com/uber-go/zap/issues/370
+func ignoreSyncErrors(err error) error {
+	var errs []error
+	for _, e := range multierr.Errors(err) {
+		if !errors.Is(e, syscall.EINVAL) && !errors.Is(e, syscall.ENOTTY) {
+			errs = append(errs, e)
+		}
+	}
+	return multierr.Combine(errs...)
+}
+
+// ShutdownOnSignal calls Shutdown with timeout, once one of sigs is received,
+// and then re-raises the signal, so the program exits as it would have without
+// the hook. sigs default to SIGINT and SIGTERM.
+//
+// Re-raising resets sigs with signal.Reset, which also removes any handlers
+// the program registered for them with signal.Notify, so the program exits
+// without them. Programs handling signals themselves should call Shutdown
+// when they are done instead.
+// It returns a func to remove the hook again, which may be called more than once.
+func ShutdownOnSignal(timeout time.Duration, sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, sigs...)
+	quit := make(chan struct{})
+
+	go func() {
+		select {
+		case sig := <-c:
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			err := Shutdown(ctx)
+			cancel()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "log: shutdown: %v\n", err)
+			}
+
+			// re-raise with the default behavior
+			signal.Reset(sigs...)
+			if p, err := os.FindProcess(os.Getpid()); err == nil && p.Signal(sig) == nil {
+				return
+			}
+			os.Exit(1)
+
+		case <-quit:
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(quit)
+		})
+	}
+}
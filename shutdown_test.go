@@ -0,0 +1,117 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type closingCore struct {
+	zapcore.Core
+	synced, closed bool
+	block          chan struct{}
+}
+
+func (c *closingCore) Sync() error {
+	if c.block != nil {
+		<-c.block
+	}
+	c.synced = true
+	return nil
+}
+
+func (c *closingCore) Close() error {
+	c.closed = true
+	return errors.New("close failed")
+}
+
+func TestShutdown(t *testing.T) {
+	prev := namedCores
+	namedCores = nil
+	t.Cleanup(func() { namedCores = prev })
+
+	restore := Replace(zap.NewNop())
+	t.Cleanup(restore)
+
+	closing := &closingCore{Core: zapcore.NewNopCore()}
+	slow := &closingCore{Core: zapcore.NewNopCore(), block: make(chan struct{})}
+	defer close(slow.block)
+
+	NamedCore("closing", closing)
+	NamedCore("slow", slow)
+	NamedCore("nop", zapcore.NewNopCore())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := Shutdown(ctx)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if !closing.synced || !closing.closed {
+		t.Errorf("expected core to be synced and closed")
+	}
+	if !strings.Contains(err.Error(), "closing: close failed") {
+		t.Errorf("expected close error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "slow: context deadline exceeded") {
+		t.Errorf("expected deadline error, got %v", err)
+	}
+	if strings.Contains(err.Error(), "nop") {
+		t.Errorf("unexpected error for nop core: %v", err)
+	}
+}
+
+type orderCore struct {
+	zapcore.Core
+	closed *bool
+	synced bool // default logger synced after the core was closed
+}
+
+func (c *orderCore) Sync() error {
+	c.synced = *c.closed
+	return nil
+}
+
+func TestShutdownOrder(t *testing.T) {
+	prev := namedCores
+	namedCores = nil
+	t.Cleanup(func() { namedCores = prev })
+
+	closing := &closingCore{Core: zapcore.NewNopCore()}
+	core := NamedCore("closing", closing)
+	unnamed := &orderCore{Core: zapcore.NewNopCore(), closed: &closing.closed}
+
+	restore := Replace(zap.New(zapcore.NewTee(core, unnamed)))
+	t.Cleanup(restore)
+
+	err := Shutdown(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "closing: close failed") {
+		t.Fatalf("expected close error, got %v", err)
+	}
+	if !unnamed.synced {
+		t.Errorf("expected default logger to be synced after closing named cores")
+	}
+
+	// closed cores are left alone
+	closing.synced, closing.closed = false, false
+	if err := Shutdown(context.Background()); err != nil {
+		t.Errorf("expected no error on second call, got %v", err)
+	}
+	if closing.synced || closing.closed {
+		t.Errorf("expected core not to be synced or closed again")
+	}
+}
+
+func TestShutdownOnSignalStop(t *testing.T) {
+	stop := ShutdownOnSignal(time.Second, os.Interrupt)
+	stop()
+	stop() // doesn't panic
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/mattes/errorstats"
+	"github.com/mattes/log"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/api/support/bundler"
@@ -27,23 +28,31 @@ var colors = map[zapcore.Level]string{
 type Config struct {
 	// Level is the minimum enabled logging level.
 	// By default, only >= errors are send to Slack.
-	Level zap.AtomicLevel
+	Level zap.AtomicLevel `yaml:"level"`
 
 	// WebhookURL is the Slack Webhook URL
-	WebhookURL string
+	WebhookURL string `yaml:"webhookURL"`
 
 	// Slack channel
-	Channel string
+	Channel string `yaml:"channel"`
 
 	// BatchDelayThreshold defines the time to wait before a batch is flushed after
 	// a message is logged.
-	BatchDelayThreshold time.Duration
+	BatchDelayThreshold time.Duration `yaml:"batchDelayThreshold"`
 
 	// BatchCountThreshold defines the maximum messages in a batch.
-	BatchCountThreshold int
+	BatchCountThreshold int `yaml:"batchCountThreshold"`
 
 	// BatchHandlerLimit sets how many batches can be processed at the same time.
-	BatchHandlerLimit int
+	BatchHandlerLimit int `yaml:"batchHandlerLimit"`
+}
+
+// register as "slack" output for log.LoadConfig
+func init() {
+	log.RegisterOutput("slack", func() log.CoreBuilder {
+		c := NewConfig()
+		return &c
+	})
 }
 
 func NewConfig() Config {
@@ -188,8 +197,9 @@ func (c *core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
 	}
 
 	// see if any errors have been logged in the meanwhile,
-	// if yes, return them here.
-	return c.errs.ErrAndReset()
+	// if yes, return them here. They belong to earlier entries,
+	// this one was accepted, see log.Failover.
+	return log.DeferredError(c.errs.ErrAndReset())
 }
 
 func (c *core) Sync() error {
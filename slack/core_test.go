@@ -0,0 +1,33 @@
+package slack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattes/log"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := log.ParseConfig([]byte(`
+outputs:
+  alerts:
+    type: slack
+    level: warn
+    webhookURL: https://hooks.slack.com/services/xxx
+    channel: "#alerts"
+    batchDelayThreshold: 5s
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := cfg.Outputs[0].Config.(*Config)
+	if c.Level.Level() != zapcore.WarnLevel ||
+		c.WebhookURL != "https://hooks.slack.com/services/xxx" ||
+		c.Channel != "#alerts" ||
+		c.BatchDelayThreshold != 5*time.Second ||
+		c.BatchCountThreshold != NewConfig().BatchCountThreshold {
+		t.Errorf("unexpected config %+v", c)
+	}
+}
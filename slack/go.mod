@@ -4,8 +4,9 @@ go 1.16
 
 require (
 	github.com/mattes/errorstats v0.0.0-20191110073129-45b03e061d62
-	go.uber.org/atomic v1.9.0 // indirect
-	go.uber.org/multierr v1.7.0 // indirect
+	github.com/mattes/log v0.0.0-20210214020244-7a8213947092
 	go.uber.org/zap v1.18.1
 	google.golang.org/api v0.51.0
 )
+
+replace github.com/mattes/log => ../
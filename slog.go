@@ -0,0 +1,292 @@
+//go:build go1.21
+// +build go1.21
+
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SlogHandler is a slog.Handler that logs through the default logger,
+// so log/slog and this package share the same cores.
+//
+//	slog.SetDefault(slog.New(log.NewSlogHandler()))
+type SlogHandler struct {
+	fields []zapcore.Field // from WithAttrs and WithGroup
+}
+
+// NewSlogHandler returns a slog.Handler that logs through the default logger.
+// Fields attached to the context with WithContext are added to every log.
+func NewSlogHandler() *SlogHandler {
+	return &SlogHandler{}
+}
+
+// Enabled implements slog.Handler.
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return defaultLogger().Core().Enabled(zapLevel(level))
+}
+
+// Handle implements slog.Handler.
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	logger := contextLogger(ctx).Desugar()
+
+	ce := logger.Check(zapLevel(r.Level), r.Message)
+	if ce == nil {
+		return nil
+	}
+
+	if !r.Time.IsZero() {
+		ce.Entry.Time = r.Time
+	}
+
+	// the caller was computed for zap, but slog knows better
+	if ce.Entry.Caller.Defined {
+		ce.Entry.Caller = zapcore.EntryCaller{}
+		if r.PC != 0 {
+			f, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+			ce.Entry.Caller = zapcore.NewEntryCaller(f.PC, f.File, f.Line, true)
+		}
+	}
+
+	fields := make([]zapcore.Field, 0, len(h.fields)+r.NumAttrs())
+	fields = append(fields, h.fields...)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendSlogAttr(fields, a)
+		return true
+	})
+
+	ce.Write(fields...)
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make([]zapcore.Field, 0, len(h.fields)+len(attrs))
+	fields = append(fields, h.fields...)
+	for _, a := range attrs {
+		fields = appendSlogAttr(fields, a)
+	}
+	return &SlogHandler{fields: fields}
+}
+
+// WithGroup implements slog.Handler.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	fields := make([]zapcore.Field, 0, len(h.fields)+1)
+	fields = append(fields, h.fields...)
+	fields = append(fields, zap.Namespace(name))
+	return &SlogHandler{fields: fields}
+}
+
+// zapLevel maps slog levels to zap levels.
+// Levels in between are rounded down, i.e. slog.LevelInfo+2 is zap.InfoLevel.
+func zapLevel(l slog.Level) zapcore.Level {
+	switch {
+	case l < slog.LevelInfo:
+		return zapcore.DebugLevel
+	case l < slog.LevelWarn:
+		return zapcore.InfoLevel
+	case l < slog.LevelError:
+		return zapcore.WarnLevel
+	default:
+		return zapcore.ErrorLevel
+	}
+}
+
+// slogLevel maps zap levels to slog levels.
+// Levels above error are mapped to slog.LevelError+1, +2, ...
+func slogLevel(l zapcore.Level) slog.Level {
+	switch l {
+	case zapcore.DebugLevel:
+		return slog.LevelDebug
+	case zapcore.InfoLevel:
+		return slog.LevelInfo
+	case zapcore.WarnLevel:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError + slog.Level(l-zapcore.ErrorLevel)
+	}
+}
+
+// appendSlogAttr appends a to fields, following the slog.Handler rules.
+func appendSlogAttr(fields []zapcore.Field, a slog.Attr) []zapcore.Field {
+	a.Value = a.Value.Resolve()
+
+	// ignore empty attrs
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	v := a.Value
+	switch v.Kind() {
+	case slog.KindString:
+		return append(fields, zap.String(a.Key, v.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(a.Key, v.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(a.Key, v.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(a.Key, v.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(a.Key, v.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(a.Key, v.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(a.Key, v.Time()))
+
+	case slog.KindGroup:
+		attrs := v.Group()
+		if len(attrs) == 0 {
+			return fields // ignore empty groups
+		}
+		if a.Key == "" {
+			// inline attrs of groups with empty key
+			for _, ga := range attrs {
+				fields = appendSlogAttr(fields, ga)
+			}
+			return fields
+		}
+		return append(fields, zap.Object(a.Key, slogGroup(attrs)))
+
+	default:
+		if err, ok := v.Any().(error); ok {
+			return append(fields, zap.NamedError(a.Key, err))
+		}
+		return append(fields, zap.Any(a.Key, v.Any()))
+	}
+}
+
+// slogGroup marshals slog group attrs as object.
+type slogGroup []slog.Attr
+
+func (g slogGroup) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, a := range g {
+		for _, f := range appendSlogAttr(nil, a) {
+			f.AddTo(enc)
+		}
+	}
+	return nil
+}
+
+// UseSlogHandler sets a default logger that logs through h, see Use.
+// It returns an error if h is a *SlogHandler, which logs through the default
+// logger itself, so every log would recurse until the stack overflows.
+// Handlers wrapping a SlogHandler can't be detected and must not be used either.
+func UseSlogHandler(h slog.Handler) error {
+	if _, ok := h.(*SlogHandler); ok {
+		return fmt.Errorf("invalid handler: SlogHandler logs through the default logger")
+	}
+
+	Use(zap.New(NewSlogCore(h),
+		zap.AddCaller(),
+		zap.AddCallerSkip(1),
+	))
+	return nil
+}
+
+// NewSlogCore returns a zapcore.Core that logs through h.
+// The logger name is added as "logger" and stacktraces as "stacktrace" attr.
+func NewSlogCore(h slog.Handler) zapcore.Core {
+	return &slogCore{handler: h}
+}
+
+type slogCore struct {
+	handler slog.Handler
+}
+
+func (c *slogCore) Enabled(level zapcore.Level) bool {
+	return c.handler.Enabled(context.Background(), slogLevel(level))
+}
+
+func (c *slogCore) With(fields []zapcore.Field) zapcore.Core {
+	h := c.handler
+
+	// a namespace nests the fields that follow, including those
+	// of later calls, which is what a slog group does
+	for i, f := range fields {
+		if f.Type == zapcore.NamespaceType {
+			if attrs := slogAttrs(fields[:i]); len(attrs) > 0 {
+				h = h.WithAttrs(attrs)
+			}
+			return (&slogCore{handler: h.WithGroup(f.Key)}).With(fields[i+1:])
+		}
+	}
+
+	if attrs := slogAttrs(fields); len(attrs) > 0 {
+		h = h.WithAttrs(attrs)
+	}
+	return &slogCore{handler: h}
+}
+
+func (c *slogCore) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checkedEntry.AddCore(entry, c)
+	}
+	return checkedEntry
+}
+
+func (c *slogCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	var pc uintptr
+	if entry.Caller.Defined {
+		pc = entry.Caller.PC
+	}
+
+	r := slog.NewRecord(entry.Time, slogLevel(entry.Level), entry.Message, pc)
+
+	if entry.LoggerName != "" {
+		r.AddAttrs(slog.String("logger", entry.LoggerName))
+	}
+
+	r.AddAttrs(slogAttrs(fields)...)
+
+	if entry.Stack != "" {
+		r.AddAttrs(slog.String("stacktrace", entry.Stack))
+	}
+
+	return c.handler.Handle(context.Background(), r)
+}
+
+func (c *slogCore) Sync() error {
+	return nil
+}
+
+// slogAttrs converts zap fields into slog attrs.
+// Fields following a namespace field are nested in a group.
+func slogAttrs(fields []zapcore.Field) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(fields))
+
+	for i, f := range fields {
+		switch f.Type {
+		case zapcore.SkipType:
+			continue
+
+		case zapcore.NamespaceType:
+			return append(attrs, slog.Attr{
+				Key:   f.Key,
+				Value: slog.GroupValue(slogAttrs(fields[i+1:])...),
+			})
+
+		case zapcore.ErrorType:
+			attrs = append(attrs, slog.Any(f.Key, f.Interface))
+
+		default:
+			// let zap figure out the value
+			enc := zapcore.NewMapObjectEncoder()
+			f.AddTo(enc)
+			if v, ok := enc.Fields[f.Key]; ok {
+				attrs = append(attrs, slog.Any(f.Key, v))
+			}
+		}
+	}
+
+	return attrs
+}
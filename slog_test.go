@@ -0,0 +1,111 @@
+//go:build go1.21
+// +build go1.21
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSlogHandler(t *testing.T) {
+	obs := setTestLogger()
+
+	logger := slog.New(NewSlogHandler()).With("service", "api").WithGroup("req")
+	logger.Warn("hello", "id", 123, slog.Group("user", "name", "gopher"), "err", errors.New("oh no"))
+
+	logs := obs.TakeAll()
+	if len(logs) != 1 {
+		t.Fatalf("expected 1 log, got %v", len(logs))
+	}
+
+	l := logs[0]
+	if l.Level != zapcore.WarnLevel || l.Message != "hello" {
+		t.Errorf("unexpected entry %v %q", l.Level, l.Message)
+	}
+	if filepath.Base(l.Caller.File) != "slog_test.go" {
+		t.Errorf("expected caller in slog_test.go, got %v", l.Caller.File)
+	}
+
+	fields := l.ContextMap()
+	if fields["service"] != "api" {
+		t.Errorf("expected service field, got %v", fields)
+	}
+
+	req, ok := fields["req"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected req group, got %v", fields)
+	}
+	if req["id"] != int64(123) || req["err"] != "oh no" {
+		t.Errorf("unexpected req group %v", req)
+	}
+	if user, ok := req["user"].(map[string]interface{}); !ok || user["name"] != "gopher" {
+		t.Errorf("unexpected user group %v", req["user"])
+	}
+}
+
+func TestSlogHandlerContext(t *testing.T) {
+	obs := setTestLogger()
+
+	ctx := WithContext(context.Background(), "requestID", "req123")
+	slog.New(NewSlogHandler()).InfoContext(ctx, "hello")
+
+	logs := obs.TakeAll()
+	if len(logs) != 1 || logs[0].ContextMap()["requestID"] != "req123" {
+		t.Errorf("expected requestID field, got %v", logs)
+	}
+}
+
+func TestUseSlogHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	restore := Replace(zap.NewNop())
+	defer restore()
+
+	if err := UseSlogHandler(slog.NewTextHandler(buf, &slog.HandlerOptions{AddSource: true})); err != nil {
+		t.Fatal(err)
+	}
+	ErrorCtx(WithContext(context.Background(), "service", "api"), "oh no", "status", 500)
+	Named("http").Info("named")
+
+	out := buf.String()
+	for _, s := range []string{"level=ERROR", `msg="oh no"`, "service=api", "status=500", "slog_test.go", "logger=http"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected %q in %q", s, out)
+		}
+	}
+}
+
+func TestUseSlogHandlerRecursion(t *testing.T) {
+	obs := setTestLogger()
+
+	if err := UseSlogHandler(NewSlogHandler()); err == nil {
+		t.Fatal("expected error")
+	}
+	Info("hello") // default logger is unchanged
+	if msgs := messages(obs); !reflect.DeepEqual(msgs, []string{"hello"}) {
+		t.Errorf("expected hello, got %v", msgs)
+	}
+}
+
+func TestSlogCoreNamespace(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := zap.New(NewSlogCore(slog.NewJSONHandler(buf, nil)))
+
+	logger.With(zap.String("service", "api"), zap.Namespace("http")).
+		With(zap.Int("status", 500)).
+		Info("request", zap.String("method", "GET"))
+
+	out := buf.String()
+	if !strings.Contains(out, `"service":"api","http":{"status":500,"method":"GET"}`) {
+		t.Errorf("expected fields nested in namespace, got %q", out)
+	}
+}
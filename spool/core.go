@@ -0,0 +1,311 @@
+// Package spool persists entries for remote cores on disk, so that they
+// survive restarts and outages of the remote service.
+package spool
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+type Config struct {
+	// Dir is the directory segment files are written to.
+	// It must not be shared with other spools or processes.
+	Dir string
+
+	// SegmentBytes starts a new segment file before the current
+	// one grows larger than this size.
+	SegmentBytes int64
+
+	// MaxBytes limits the size of all segment files. The oldest segment
+	// is deleted, even if it wasn't shipped, to make room for new entries.
+	MaxBytes int64
+
+	// SyncInterval commits written entries to disk in this interval.
+	// Zero commits every entry, which is safe but slow.
+	SyncInterval time.Duration
+
+	// Retry is the interval shipping is retried after the wrapped core failed.
+	Retry time.Duration
+
+	// BatchSize is the number of entries written to the wrapped core
+	// before it's synced and the entries are acknowledged.
+	BatchSize int
+}
+
+func NewConfig() Config {
+	return Config{
+		SegmentBytes: 8 << 20,   // 8 MB
+		MaxBytes:     256 << 20, // 256 MB
+		SyncInterval: time.Second,
+		Retry:        5 * time.Second,
+		BatchSize:    100,
+	}
+}
+
+// Build wraps core with a spool. Entries left over from
+// previous runs are shipped right away.
+func (cfg Config) Build(core zapcore.Core) (*Core, error) {
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("missing Dir")
+	}
+	if cfg.SegmentBytes <= 0 {
+		return nil, fmt.Errorf("invalid SegmentBytes")
+	}
+	if cfg.MaxBytes < cfg.SegmentBytes {
+		return nil, fmt.Errorf("invalid MaxBytes, must be at least SegmentBytes")
+	}
+	if cfg.SyncInterval < 0 {
+		return nil, fmt.Errorf("invalid SyncInterval")
+	}
+	if cfg.Retry <= 0 {
+		return nil, fmt.Errorf("invalid Retry")
+	}
+	if cfg.BatchSize <= 0 {
+		return nil, fmt.Errorf("invalid BatchSize")
+	}
+
+	w, err := openWAL(cfg.Dir, cfg.SegmentBytes, cfg.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &shipper{
+		cfg:  cfg,
+		core: core,
+		wal:  w,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go s.run()
+
+	return &Core{core: core, shipper: s}, nil
+}
+
+// Core is a core that appends entries to segment files on disk. They are
+// written to the wrapped core in the background, and deleted once
+// the wrapped core synced them successfully. If shipping fails, it's
+// retried until it succeeds, or the entries are dropped to stay within
+// MaxBytes. Entries that weren't shipped when the process exits are
+// shipped on the next start.
+//
+// Entries are shipped at least once, an entry might be written twice
+// if the process exits after writing but before the entry was acknowledged.
+// A corrupted entry, i.e. from a torn write, drops the rest of its segment.
+//
+// Fields keep their type, errors keep their message, type, causes and stack.
+// Object and array fields are replayed with the types of their JSON values,
+// i.e. durations in an object become int64. Custom fields of cores, like
+// googleStackdriver.Request, are supported for basic types and *http.Request
+// without its body. Other custom fields are dropped and Write returns an error.
+// The caller's program counter is only kept if the entry is shipped
+// by the same process, its function name is always kept.
+//
+//	c := spool.NewConfig()
+//	c.Dir = "/var/spool/my-service/stackdriver"
+//	s, err := c.Build(stackdriverCore)
+//	logger := zap.New(s)
+type Core struct {
+	core    zapcore.Core // the wrapped core without context fields
+	context []zapcore.Field
+	shipper *shipper // shared with clones
+}
+
+// Stats are the counts of a spool since it was built.
+type Stats struct {
+	Segments  int   `json:"segments"`
+	Bytes     int64 `json:"bytes"`
+	Appended  int64 `json:"appended"`
+	Shipped   int64 `json:"shipped"`
+	Dropped   int64 `json:"dropped"`
+	Corrupted int64 `json:"corrupted"` // skipped entries and segment tails
+}
+
+func (c *Core) Enabled(level zapcore.Level) bool {
+	return c.core.Enabled(level)
+}
+
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	return &Core{
+		core:    c.core,
+		context: append(c.context[:len(c.context):len(c.context)], fields...),
+		shipper: c.shipper,
+	}
+}
+
+func (c *Core) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.core.Check(entry, nil) != nil {
+		return checkedEntry.AddCore(entry, c)
+	}
+	return checkedEntry
+}
+
+func (c *Core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	if len(c.context) > 0 {
+		fields = append(c.context[:len(c.context):len(c.context)], fields...)
+	}
+
+	payload, fieldErrs := encodeRecord(entry, fields)
+	if payload == nil {
+		return fieldErrs
+	}
+	if err := c.shipper.wal.append(payload); err != nil {
+		return err
+	}
+
+	if c.shipper.cfg.SyncInterval == 0 {
+		return multierr.Append(fieldErrs, c.shipper.wal.sync())
+	}
+	return fieldErrs
+}
+
+// Sync commits written entries to disk and returns the last error
+// of the wrapped core since the last Sync. It doesn't wait for
+// entries to be shipped.
+func (c *Core) Sync() error {
+	return multierr.Append(c.shipper.wal.sync(), c.shipper.takeErr())
+}
+
+// Close ships the remaining entries, unless the wrapped core fails, and
+// closes the spool and the wrapped core, if it implements Close() error.
+// It's called by log.Shutdown.
+func (c *Core) Close() error {
+	c.shipper.once.Do(func() { close(c.shipper.stop) })
+	<-c.shipper.done
+
+	err := multierr.Append(c.shipper.wal.close(), c.shipper.takeErr())
+	if cl, ok := c.core.(interface{ Close() error }); ok {
+		err = multierr.Append(err, cl.Close())
+	}
+	return err
+}
+
+// Stats returns the counts of the spool.
+func (c *Core) Stats() Stats {
+	w := c.shipper.wal
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return Stats{
+		Segments:  len(w.segments),
+		Bytes:     w.bytes,
+		Appended:  w.appended,
+		Shipped:   w.shipped,
+		Dropped:   w.dropped,
+		Corrupted: w.corrupted,
+	}
+}
+
+// shipper writes the entries in the wal to the wrapped core.
+type shipper struct {
+	cfg  Config
+	core zapcore.Core
+	wal  *wal
+
+	once sync.Once
+	stop chan struct{}
+	done chan struct{}
+
+	mu  sync.Mutex
+	err error // last error of the wrapped core
+}
+
+func (s *shipper) run() {
+	defer close(s.done)
+
+	var tick <-chan time.Time
+	if s.cfg.SyncInterval > 0 {
+		t := time.NewTicker(s.cfg.SyncInterval)
+		defer t.Stop()
+		tick = t.C
+	}
+
+	retry := time.NewTimer(s.cfg.Retry)
+	retry.Stop()
+	defer retry.Stop()
+	failed := false
+
+	for {
+		select {
+		case <-s.wal.notify:
+			if failed {
+				continue // wait for retry
+			}
+
+		case <-retry.C:
+
+		case <-tick:
+			if err := s.wal.sync(); err != nil {
+				s.setErr(err)
+			}
+			continue
+
+		case <-s.stop:
+			if !failed {
+				s.setErr(s.ship())
+			}
+			return
+		}
+
+		err := s.ship()
+		s.setErr(err)
+		failed = err != nil
+		if failed {
+			retry.Reset(s.cfg.Retry)
+		}
+	}
+}
+
+// ship writes and acknowledges batches until all entries are shipped.
+func (s *shipper) ship() error {
+	for {
+		from := s.wal.position()
+		payloads, to, err := s.wal.read(from, s.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+		if to == from {
+			return nil
+		}
+
+		for _, p := range payloads {
+			entry, fields, err := decodeRecord(p)
+			if err != nil {
+				s.wal.corrupt() // checksum was valid, but it can't be decoded
+				continue
+			}
+			if err := s.core.Write(entry, fields); err != nil {
+				return err
+			}
+		}
+		if len(payloads) > 0 {
+			if err := s.core.Sync(); err != nil {
+				return err
+			}
+		}
+
+		if err := s.wal.ack(from, to, len(payloads)); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *shipper) setErr(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+func (s *shipper) takeErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.err
+	s.err = nil
+	return err
+}
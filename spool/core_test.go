@@ -0,0 +1,209 @@
+package spool
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// remoteCore fails writes while down is set.
+type remoteCore struct {
+	zapcore.Core
+
+	mu   sync.Mutex
+	down bool
+}
+
+func (c *remoteCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.down {
+		return errors.New("remote unavailable")
+	}
+	return c.Core.Write(entry, fields)
+}
+
+func (c *remoteCore) setDown(down bool) {
+	c.mu.Lock()
+	c.down = down
+	c.mu.Unlock()
+}
+
+func testConfig(dir string) Config {
+	c := NewConfig()
+	c.Dir = dir
+	c.Retry = 10 * time.Millisecond
+	return c
+}
+
+// waitShipped waits until n entries were shipped.
+func waitShipped(t *testing.T, s *Core, n int64) {
+	deadline := time.Now().Add(time.Second)
+	for s.Stats().Shipped < n && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if st := s.Stats(); st.Shipped != n {
+		t.Fatalf("expected %v shipped entries, got %+v", n, st)
+	}
+}
+
+func messages(obs *observer.ObservedLogs) []string {
+	msgs := make([]string, 0)
+	for _, l := range obs.TakeAll() {
+		msgs = append(msgs, l.Message)
+	}
+	return msgs
+}
+
+func TestCore(t *testing.T) {
+	core, obs := observer.New(zapcore.InfoLevel)
+	s, err := testConfig(t.TempDir()).Build(core)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	logger := zap.New(s, zap.AddCaller()).Named("api").With(zap.String("service", "billing"))
+	logger.Info("hello", zap.Int("attempt", 2), zap.Float64("ratio", 0.5), zap.Strings("tags", []string{"a"}))
+	logger.Debug("not enabled")
+
+	waitShipped(t, s, 1)
+	logs := obs.TakeAll()
+	if len(logs) != 1 {
+		t.Fatalf("expected one entry, got %v", logs)
+	}
+
+	l := logs[0]
+	if l.Message != "hello" || l.LoggerName != "api" || l.Level != zapcore.InfoLevel || !l.Caller.Defined {
+		t.Errorf("unexpected entry %+v", l.Entry)
+	}
+	expect := map[string]interface{}{
+		"service": "billing",
+		"attempt": int64(2),
+		"ratio":   0.5,
+		"tags":    []interface{}{"a"},
+	}
+	if ctx := l.ContextMap(); !reflect.DeepEqual(ctx, expect) {
+		t.Errorf("expected fields %v, got %v", expect, ctx)
+	}
+
+	if st := s.Stats(); st.Segments != 1 || st.Appended != 1 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
+
+func TestCoreReplay(t *testing.T) {
+	dir := t.TempDir()
+	core, obs := observer.New(zapcore.InfoLevel)
+	remote := &remoteCore{Core: core, down: true}
+
+	s, err := testConfig(dir).Build(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.New(s)
+	logger.Info("1")
+	logger.Info("2")
+
+	if err := s.Close(); err == nil {
+		t.Errorf("expected error of wrapped core")
+	}
+	if obs.Len() != 0 {
+		t.Fatalf("expected no shipped entries, got %v", obs.All())
+	}
+
+	// next start
+	remote.setDown(false)
+	s, err = testConfig(dir).Build(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	zap.New(s).Info("3")
+
+	waitShipped(t, s, 3)
+	if msgs, expect := messages(obs), []string{"1", "2", "3"}; !reflect.DeepEqual(msgs, expect) {
+		t.Errorf("expected %v, got %v", expect, msgs)
+	}
+}
+
+func TestCoreRetry(t *testing.T) {
+	core, obs := observer.New(zapcore.InfoLevel)
+	remote := &remoteCore{Core: core, down: true}
+
+	c := testConfig(t.TempDir())
+	c.SegmentBytes = 100
+	s, err := c.Build(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	logger := zap.New(s)
+	for i := 0; i < 5; i++ {
+		logger.Info("hello")
+	}
+	if st := s.Stats(); st.Segments < 3 || st.Shipped != 0 {
+		t.Fatalf("expected multiple unshipped segments, got %+v", st)
+	}
+
+	remote.setDown(false)
+	waitShipped(t, s, 5)
+	if obs.Len() != 5 {
+		t.Errorf("expected 5 entries, got %v", obs.Len())
+	}
+	if st := s.Stats(); st.Segments != 1 {
+		t.Errorf("expected shipped segments to be deleted, got %+v", st)
+	}
+}
+
+func TestCoreMaxBytes(t *testing.T) {
+	core, obs := observer.New(zapcore.InfoLevel)
+	remote := &remoteCore{Core: core, down: true}
+
+	c := testConfig(t.TempDir())
+	c.SegmentBytes = 200
+	c.MaxBytes = 400
+	s, err := c.Build(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	logger := zap.New(s)
+	for i := 0; i < 20; i++ {
+		logger.Info("hello", zap.Int("i", i))
+	}
+
+	st := s.Stats()
+	if st.Bytes > c.MaxBytes || st.Dropped == 0 {
+		t.Fatalf("expected oldest entries to be dropped, got %+v", st)
+	}
+
+	remote.setDown(false)
+	waitShipped(t, s, st.Appended-st.Dropped)
+
+	logs := obs.TakeAll()
+	if last := logs[len(logs)-1].ContextMap()["i"]; last != int64(19) {
+		t.Errorf("expected newest entries to be kept, got %v", last)
+	}
+}
+
+func TestConfigBuild(t *testing.T) {
+	c := NewConfig()
+	if _, err := c.Build(zapcore.NewNopCore()); err == nil {
+		t.Error("expected error for missing Dir")
+	}
+
+	c.Dir = t.TempDir()
+	c.MaxBytes = c.SegmentBytes - 1
+	if _, err := c.Build(zapcore.NewNopCore()); err == nil {
+		t.Error("expected error for invalid MaxBytes")
+	}
+}
@@ -0,0 +1,550 @@
+package spool
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/mattes/log"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// record is an entry with its fields as stored in the spool.
+type record struct {
+	Level   zapcore.Level `json:"level"`
+	Time    time.Time     `json:"time"`
+	Logger  string        `json:"logger,omitempty"`
+	Message string        `json:"msg"`
+	Caller  *recordCaller `json:"caller,omitempty"`
+	Stack   string        `json:"stack,omitempty"`
+	Fields  []recordField `json:"fields,omitempty"`
+
+	// Process identifies the process that wrote the record,
+	// program counters are only valid within the same process.
+	Process string `json:"process"`
+}
+
+type recordCaller struct {
+	PC       uintptr `json:"pc,omitempty"`
+	File     string  `json:"file"`
+	Line     int     `json:"line"`
+	Function string  `json:"function,omitempty"`
+}
+
+// recordField is a zapcore.Field, Interface is stored as Value,
+// which is decoded depending on Kind.
+type recordField struct {
+	Key     string            `json:"key,omitempty"`
+	Type    zapcore.FieldType `json:"type"`
+	Integer int64             `json:"int,omitempty"`
+	String  string            `json:"str,omitempty"`
+	Kind    string            `json:"kind,omitempty"`
+	Value   json.RawMessage   `json:"value,omitempty"`
+}
+
+// recordError is an error with its chain of causes.
+type recordError struct {
+	Message string        `json:"msg"`
+	Type    string        `json:"type"`
+	Verbose string        `json:"verbose,omitempty"` // %+v, if it differs from Message
+	Stack   []recordFrame `json:"stack,omitempty"`
+	Cause   *recordError  `json:"cause,omitempty"`
+}
+
+type recordFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// recordRequest is a *http.Request without its body.
+type recordRequest struct {
+	Method        string      `json:"method"`
+	URL           string      `json:"url"`
+	Proto         string      `json:"proto"`
+	Header        http.Header `json:"header,omitempty"`
+	Host          string      `json:"host,omitempty"`
+	RemoteAddr    string      `json:"remoteAddr,omitempty"`
+	ContentLength int64       `json:"contentLength,omitempty"`
+}
+
+var process = func() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}()
+
+// encodeRecord encodes entry and fields. Fields that can't be encoded,
+// like zapcore.SkipType fields with unknown values, are left out and
+// returned as error.
+func encodeRecord(entry zapcore.Entry, fields []zapcore.Field) ([]byte, error) {
+	r := record{
+		Level:   entry.Level,
+		Time:    entry.Time,
+		Logger:  entry.LoggerName,
+		Message: entry.Message,
+		Stack:   entry.Stack,
+		Process: process,
+	}
+	if entry.Caller.Defined {
+		r.Caller = &recordCaller{
+			PC:       entry.Caller.PC,
+			File:     entry.Caller.File,
+			Line:     entry.Caller.Line,
+			Function: entry.Caller.Function,
+		}
+		if r.Caller.Function == "" {
+			if fn := runtime.FuncForPC(entry.Caller.PC); fn != nil {
+				r.Caller.Function = fn.Name()
+			}
+		}
+	}
+
+	var errs error
+	for _, f := range fields {
+		rf, err := encodeField(f)
+		if err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("spool: dropped field %q: %w", f.Key, err))
+			continue
+		}
+		r.Fields = append(r.Fields, rf)
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return data, errs
+}
+
+// decodeRecord returns the entry and its fields.
+func decodeRecord(data []byte) (zapcore.Entry, []zapcore.Field, error) {
+	var r record
+	if err := unmarshal(data, &r); err != nil {
+		return zapcore.Entry{}, nil, err
+	}
+
+	entry := zapcore.Entry{
+		Level:      r.Level,
+		Time:       r.Time,
+		LoggerName: r.Logger,
+		Message:    r.Message,
+		Stack:      r.Stack,
+	}
+	if r.Caller != nil {
+		entry.Caller = zapcore.EntryCaller{
+			Defined:  true,
+			File:     r.Caller.File,
+			Line:     r.Caller.Line,
+			Function: r.Caller.Function,
+		}
+		if r.Process == process {
+			entry.Caller.PC = r.Caller.PC
+		}
+	}
+
+	fields := make([]zapcore.Field, 0, len(r.Fields))
+	for _, rf := range r.Fields {
+		f, err := decodeField(rf)
+		if err != nil {
+			return zapcore.Entry{}, nil, err
+		}
+		fields = append(fields, f)
+	}
+	return entry, fields, nil
+}
+
+var errUnsupported = errors.New("unsupported value")
+
+func encodeField(f zapcore.Field) (recordField, error) {
+	rf := recordField{Key: f.Key, Type: f.Type, Integer: f.Integer, String: f.String}
+
+	var v interface{}
+	switch f.Type {
+	case zapcore.ErrorType, zapcore.InlineMarshalerType:
+		if err, ok := log.FieldError(f); ok {
+			rf.Kind, v = "error", encodeError(err)
+			break
+		}
+		if f.Type == zapcore.ErrorType {
+			return rf, errUnsupported
+		}
+		rf.Kind, v = "object", marshalObject(f.Interface.(zapcore.ObjectMarshaler))
+
+	case zapcore.ObjectMarshalerType:
+		rf.Kind, v = "object", marshalObject(f.Interface.(zapcore.ObjectMarshaler))
+
+	case zapcore.ArrayMarshalerType:
+		enc := zapcore.NewMapObjectEncoder()
+		enc.AddArray("array", f.Interface.(zapcore.ArrayMarshaler))
+		rf.Kind, v = "array", enc.Fields["array"]
+
+	case zapcore.BinaryType, zapcore.ByteStringType:
+		rf.Kind, v = "bytes", f.Interface
+
+	case zapcore.Complex128Type:
+		c := f.Interface.(complex128)
+		rf.Kind, v = "complex", []float64{real(c), imag(c)}
+
+	case zapcore.Complex64Type:
+		c := f.Interface.(complex64)
+		rf.Kind, v = "complex", []float64{float64(real(c)), float64(imag(c))}
+
+	case zapcore.StringerType:
+		rf.Type, rf.String = zapcore.StringType, fmt.Sprint(f.Interface)
+
+	case zapcore.ReflectType:
+		rf.Kind, v = "json", f.Interface
+
+	case zapcore.TimeType:
+		if loc, ok := f.Interface.(*time.Location); ok {
+			rf.Kind, v = "location", loc.String()
+		}
+
+	case zapcore.TimeFullType:
+		rf.Kind, v = "time", f.Interface
+
+	case zapcore.SkipType:
+		// custom fields of cores, like googleStackdriver.Request
+		if f.Interface == nil {
+			break
+		}
+		switch i := f.Interface.(type) {
+		case bool:
+			rf.Kind = "bool"
+		case int:
+			rf.Kind = "int"
+		case int64:
+			rf.Kind = "int64"
+		case float64:
+			rf.Kind = "float64"
+		case string:
+			rf.Kind = "string"
+		case time.Duration:
+			rf.Kind = "duration"
+		case *http.Request:
+			rf.Kind, v = "request", encodeRequest(i)
+		default:
+			return rf, fmt.Errorf("%w %T", errUnsupported, f.Interface)
+		}
+		if v == nil {
+			v = f.Interface
+		}
+	}
+
+	if rf.Kind != "" {
+		value, err := json.Marshal(v)
+		if err != nil {
+			return rf, err
+		}
+		rf.Value = value
+	}
+	return rf, nil
+}
+
+func decodeField(rf recordField) (zapcore.Field, error) {
+	f := zapcore.Field{Key: rf.Key, Type: rf.Type, Integer: rf.Integer, String: rf.String}
+
+	var err error
+	switch rf.Kind {
+	case "":
+
+	case "error":
+		var re recordError
+		if err = unmarshal(rf.Value, &re); err == nil {
+			if rf.Type == zapcore.InlineMarshalerType {
+				return log.NamedErr(rf.Key, re.err()), nil
+			}
+			f.Interface = re.err()
+		}
+
+	case "object":
+		var m map[string]interface{}
+		err = unmarshal(rf.Value, &m)
+		f.Interface = replayedObject(m)
+
+	case "array":
+		var a []interface{}
+		err = unmarshal(rf.Value, &a)
+		f.Interface = replayedArray(a)
+
+	case "bytes":
+		var b []byte
+		err = json.Unmarshal(rf.Value, &b)
+		f.Interface = b
+
+	case "complex":
+		var c [2]float64
+		err = json.Unmarshal(rf.Value, &c)
+		if rf.Type == zapcore.Complex64Type {
+			f.Interface = complex(float32(c[0]), float32(c[1]))
+		} else {
+			f.Interface = complex(c[0], c[1])
+		}
+
+	case "json":
+		var v interface{}
+		err = unmarshal(rf.Value, &v)
+		f.Interface = v
+
+	case "location":
+		var name string
+		if err = json.Unmarshal(rf.Value, &name); err == nil {
+			loc, lerr := time.LoadLocation(name)
+			if lerr != nil {
+				loc = time.UTC
+			}
+			f.Interface = loc
+		}
+
+	case "time":
+		var t time.Time
+		err = json.Unmarshal(rf.Value, &t)
+		f.Interface = t
+
+	case "bool":
+		var b bool
+		err = json.Unmarshal(rf.Value, &b)
+		f.Interface = b
+
+	case "int":
+		var i int
+		err = json.Unmarshal(rf.Value, &i)
+		f.Interface = i
+
+	case "int64":
+		var i int64
+		err = json.Unmarshal(rf.Value, &i)
+		f.Interface = i
+
+	case "float64":
+		var x float64
+		err = json.Unmarshal(rf.Value, &x)
+		f.Interface = x
+
+	case "string":
+		var s string
+		err = json.Unmarshal(rf.Value, &s)
+		f.Interface = s
+
+	case "duration":
+		var d time.Duration
+		err = json.Unmarshal(rf.Value, &d)
+		f.Interface = d
+
+	case "request":
+		var rr recordRequest
+		if err = json.Unmarshal(rf.Value, &rr); err == nil {
+			f.Interface, err = rr.request()
+		}
+
+	default:
+		err = fmt.Errorf("unknown kind %q", rf.Kind)
+	}
+
+	if err != nil {
+		return zapcore.Field{}, fmt.Errorf("field %q: %w", rf.Key, err)
+	}
+	return f, nil
+}
+
+// unmarshal decodes numbers as int64 or float64.
+func unmarshal(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if p, ok := v.(*interface{}); ok {
+		*p = number(*p)
+	}
+	if p, ok := v.(*map[string]interface{}); ok {
+		number(*p)
+	}
+	if p, ok := v.(*[]interface{}); ok {
+		number(*p)
+	}
+	return nil
+}
+
+// number converts json.Number values to int64 or float64.
+func number(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = number(v[k])
+		}
+
+	case []interface{}:
+		for i := range v {
+			v[i] = number(v[i])
+		}
+	}
+	return v
+}
+
+func marshalObject(m zapcore.ObjectMarshaler) map[string]interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	m.MarshalLogObject(enc)
+	return enc.Fields
+}
+
+// replayedObject is an object field decoded from the spool.
+type replayedObject map[string]interface{}
+
+func (o replayedObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	keys := make([]string, 0, len(o))
+	for k := range o {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var err error
+	for _, k := range keys {
+		switch v := o[k].(type) {
+		case map[string]interface{}:
+			err = multierr.Append(err, enc.AddObject(k, replayedObject(v)))
+		case []interface{}:
+			err = multierr.Append(err, enc.AddArray(k, replayedArray(v)))
+		default:
+			zap.Any(k, v).AddTo(enc)
+		}
+	}
+	return err
+}
+
+// replayedArray is an array field decoded from the spool.
+type replayedArray []interface{}
+
+func (a replayedArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	var err error
+	for _, v := range a {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			err = multierr.Append(err, enc.AppendObject(replayedObject(v)))
+		case []interface{}:
+			err = multierr.Append(err, enc.AppendArray(replayedArray(v)))
+		default:
+			err = multierr.Append(err, enc.AppendReflected(v))
+		}
+	}
+	return err
+}
+
+// encodeError encodes the chain of err. The stack is stored with the
+// outermost error, which is where log.StackOf finds it again.
+func encodeError(err error) *recordError {
+	var head, tail *recordError
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		re := &recordError{Message: e.Error(), Type: fmt.Sprintf("%T", e)}
+		if t, ok := e.(interface{ ErrorType() string }); ok {
+			re.Type = t.ErrorType()
+		}
+		if _, ok := e.(fmt.Formatter); ok {
+			if v := fmt.Sprintf("%+v", e); v != re.Message {
+				re.Verbose = v
+			}
+		}
+
+		if head == nil {
+			head = re
+			for _, f := range log.StackOf(err) {
+				head.Stack = append(head.Stack, recordFrame{Function: f.Function, File: f.File, Line: f.Line})
+			}
+		} else {
+			tail.Cause = re
+		}
+		tail = re
+	}
+	return head
+}
+
+func (re *recordError) err() *replayedError {
+	e := &replayedError{msg: re.Message, typ: re.Type, verbose: re.Verbose}
+	for _, f := range re.Stack {
+		e.frames = append(e.frames, runtime.Frame{Function: f.Function, File: f.File, Line: f.Line})
+	}
+	if re.Cause != nil {
+		e.cause = re.Cause.err()
+	}
+	return e
+}
+
+// replayedError is an error decoded from the spool. It reports the type,
+// causes and stack of the original error to the log package.
+type replayedError struct {
+	msg, typ, verbose string
+	frames            []runtime.Frame
+	cause             *replayedError
+}
+
+func (e *replayedError) Error() string                { return e.msg }
+func (e *replayedError) ErrorType() string            { return e.typ }
+func (e *replayedError) StackFrames() []runtime.Frame { return e.frames }
+
+func (e *replayedError) Unwrap() error {
+	if e.cause == nil {
+		return nil
+	}
+	return e.cause
+}
+
+// Format writes the verbose message of the original error for %+v.
+func (e *replayedError) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') && e.verbose != "" {
+		fmt.Fprint(s, e.verbose)
+		return
+	}
+	fmt.Fprint(s, e.msg)
+}
+
+func encodeRequest(r *http.Request) recordRequest {
+	rr := recordRequest{
+		Method:        r.Method,
+		Proto:         r.Proto,
+		Header:        r.Header,
+		Host:          r.Host,
+		RemoteAddr:    r.RemoteAddr,
+		ContentLength: r.ContentLength,
+	}
+	if r.URL != nil {
+		rr.URL = r.URL.String()
+	}
+	return rr
+}
+
+func (rr recordRequest) request() (*http.Request, error) {
+	u, err := url.Parse(rr.URL)
+	if err != nil {
+		return nil, err
+	}
+	major, minor, _ := http.ParseHTTPVersion(rr.Proto)
+	return &http.Request{
+		Method:        rr.Method,
+		URL:           u,
+		Proto:         rr.Proto,
+		ProtoMajor:    major,
+		ProtoMinor:    minor,
+		Header:        rr.Header,
+		Host:          rr.Host,
+		RemoteAddr:    rr.RemoteAddr,
+		ContentLength: rr.ContentLength,
+	}, nil
+}
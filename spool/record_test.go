@@ -0,0 +1,161 @@
+package spool
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattes/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// fieldsCore keeps the fields of written entries, like remote cores see them.
+type fieldsCore struct {
+	zapcore.LevelEnabler
+
+	mu     sync.Mutex
+	fields [][]zapcore.Field
+}
+
+func (c *fieldsCore) With([]zapcore.Field) zapcore.Core { return c }
+func (c *fieldsCore) Sync() error                       { return nil }
+
+func (c *fieldsCore) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checkedEntry.AddCore(entry, c)
+	}
+	return checkedEntry
+}
+
+func (c *fieldsCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	c.mu.Lock()
+	c.fields = append(c.fields, fields)
+	c.mu.Unlock()
+	return nil
+}
+
+// customField is a field like the custom fields of googleStackdriver.
+func customField(key string, v interface{}) zapcore.Field {
+	return zapcore.Field{Key: key, Type: zapcore.SkipType, Interface: v}
+}
+
+type stackError struct {
+	pcs []uintptr
+}
+
+func (e *stackError) Error() string      { return "connection reset" }
+func (e *stackError) Callers() []uintptr { return e.pcs }
+
+func TestRecordFields(t *testing.T) {
+	core := &fieldsCore{LevelEnabler: zapcore.InfoLevel}
+	s, err := testConfig(t.TempDir()).Build(core)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	pcs := make([]uintptr, 32)
+	cause := &stackError{pcs: pcs[:runtime.Callers(1, pcs)]}
+	err = fmt.Errorf("request failed: %w", cause)
+
+	req := httptest.NewRequest("POST", "https://example.com/charge?id=1", nil)
+	req.Header.Set("User-Agent", "test")
+	at := time.Date(2021, 8, 1, 10, 0, 0, 0, time.UTC)
+
+	zap.New(s).Error("failed",
+		customField("request", req),
+		customField("requestLatency", 3*time.Second),
+		customField("requestSize", int64(42)),
+		customField("requestStatus", 500),
+		zap.Error(err),
+		log.NamedErr("cause", err),
+		zap.Duration("elapsed", time.Second),
+		zap.Time("at", at),
+	)
+	waitShipped(t, s, 1)
+
+	fields := map[string]zapcore.Field{}
+	for _, f := range core.fields[0] {
+		fields[f.Key] = f
+	}
+
+	r, ok := fields["request"].Interface.(*http.Request)
+	if !ok || fields["request"].Type != zapcore.SkipType {
+		t.Fatalf("expected custom request field, got %#v", fields["request"])
+	}
+	if r.Method != "POST" || r.URL.String() != "https://example.com/charge?id=1" || r.UserAgent() != "test" || r.ProtoMajor != 1 {
+		t.Errorf("unexpected request %+v", r)
+	}
+	if d, ok := fields["requestLatency"].Interface.(time.Duration); !ok || d != 3*time.Second {
+		t.Errorf("unexpected latency %#v", fields["requestLatency"].Interface)
+	}
+	if fields["requestSize"].Interface != int64(42) || fields["requestStatus"].Interface != 500 {
+		t.Errorf("unexpected custom fields %#v, %#v", fields["requestSize"].Interface, fields["requestStatus"].Interface)
+	}
+
+	if f := fields["elapsed"]; f.Type != zapcore.DurationType || f.Integer != int64(time.Second) {
+		t.Errorf("unexpected duration field %#v", f)
+	}
+	if f := fields["at"]; f.Type != zapcore.TimeType || f.Integer != at.UnixNano() || f.Interface != time.UTC {
+		t.Errorf("unexpected time field %#v", f)
+	}
+
+	// errors keep their chain, types and stack
+	replayed, ok := fields["error"].Interface.(error)
+	if !ok || fields["error"].Type != zapcore.ErrorType || replayed.Error() != err.Error() {
+		t.Fatalf("unexpected error field %#v", fields["error"])
+	}
+	if u := errors.Unwrap(replayed); u == nil || u.Error() != "connection reset" {
+		t.Errorf("expected cause, got %v", u)
+	}
+	if !reflect.DeepEqual(frameFunctions(log.StackOf(replayed)), frameFunctions(log.StackOf(err))) {
+		t.Errorf("expected stack %v, got %v", log.StackOf(err), log.StackOf(replayed))
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	fields["cause"].AddTo(enc)
+	expect := zapcore.NewMapObjectEncoder()
+	log.NamedErr("cause", err).AddTo(expect)
+	if !reflect.DeepEqual(enc.Fields, expect.Fields) {
+		t.Errorf("expected structured error %v, got %v", expect.Fields, enc.Fields)
+	}
+}
+
+func frameFunctions(frames []runtime.Frame) []string {
+	var fns []string
+	for _, f := range frames {
+		fns = append(fns, f.Function)
+	}
+	return fns
+}
+
+func TestRecordUnsupportedField(t *testing.T) {
+	core, obs := observer.New(zapcore.InfoLevel)
+	s, err := testConfig(t.TempDir()).Build(core)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	err = s.Write(zapcore.Entry{Level: zapcore.InfoLevel, Message: "hello"}, []zapcore.Field{
+		customField("unknown", struct{}{}),
+		zap.String("a", "b"),
+	})
+	if err == nil || !strings.Contains(err.Error(), `dropped field "unknown"`) {
+		t.Errorf("expected error for dropped field, got %v", err)
+	}
+
+	waitShipped(t, s, 1)
+	if ctx := obs.All()[0].ContextMap(); !reflect.DeepEqual(ctx, map[string]interface{}{"a": "b"}) {
+		t.Errorf("unexpected fields %v", ctx)
+	}
+}
@@ -0,0 +1,472 @@
+package spool
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/multierr"
+)
+
+const (
+	segmentExt     = ".wal"
+	checkpointName = "checkpoint"
+
+	// a frame is the payload length, the CRC-32C of the payload and the payload
+	frameHeaderSize = 8
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// position is the start of the next record to ship.
+type position struct {
+	seq uint64 // segment
+	off int64  // offset in segment
+	n   int    // records before off
+}
+
+type segment struct {
+	seq     uint64
+	size    int64
+	records int // valid records
+}
+
+// wal is a write-ahead log of records, split into segment files named by
+// their sequence number. Records are appended to the last segment,
+// the others are sealed. Segments are deleted, once their records are
+// acknowledged, or when the log grows larger than maxBytes.
+type wal struct {
+	dir          string
+	segmentBytes int64
+	maxBytes     int64
+
+	mu       sync.Mutex
+	segments []segment // sorted by seq, the last one is active
+	active   *os.File
+	bytes    int64    // size of all segments
+	pos      position // acknowledged position, saved in the checkpoint file
+	dirty    bool     // writes since the last fsync
+	closed   bool
+	notify   chan struct{} // signals appended records
+
+	appended, shipped, dropped, corrupted int64
+}
+
+func openWAL(dir string, segmentBytes, maxBytes int64) (*wal, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	w := &wal{
+		dir:          dir,
+		segmentBytes: segmentBytes,
+		maxBytes:     maxBytes,
+		notify:       make(chan struct{}, 1),
+	}
+
+	if err := w.recover(); err != nil {
+		return nil, err
+	}
+
+	// never append to segments of previous runs, they might end with a torn write
+	var seq uint64 = 1
+	if len(w.segments) > 0 {
+		seq = w.segments[len(w.segments)-1].seq + 1
+	}
+	if err := w.create(seq); err != nil {
+		return nil, err
+	}
+	if len(w.segments) == 1 {
+		w.pos = position{seq: seq}
+	}
+
+	if w.pending() {
+		w.signal() // replay
+	}
+	return w, nil
+}
+
+// recover reads the segments and the checkpoint of previous runs
+// and deletes acknowledged segments.
+func (w *wal) recover() error {
+	files, err := ioutil.ReadDir(w.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name(), segmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(f.Name(), segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		w.segments = append(w.segments, segment{seq: seq, size: f.Size()})
+	}
+	sort.Slice(w.segments, func(i, j int) bool { return w.segments[i].seq < w.segments[j].seq })
+
+	if len(w.segments) > 0 {
+		w.pos = position{seq: w.segments[0].seq}
+	}
+	if p, err := w.loadCheckpoint(); err == nil {
+		w.pos = p
+	}
+
+	// delete acknowledged segments and count the records of the others
+	segments := w.segments[:0]
+	for _, s := range w.segments {
+		if s.seq < w.pos.seq {
+			if err := os.Remove(w.path(s.seq)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+
+		records, valid, err := w.count(s.seq)
+		if err != nil {
+			return err
+		}
+		s.records = len(records)
+		if valid < s.size {
+			// i.e. a torn write when the process was killed
+			if err := os.Truncate(w.path(s.seq), valid); err != nil {
+				return err
+			}
+			s.size = valid
+			w.corrupted++
+		}
+		if s.seq == w.pos.seq {
+			for _, off := range records {
+				if off < w.pos.off {
+					w.pos.n++
+				}
+			}
+		}
+
+		w.bytes += s.size
+		segments = append(segments, s)
+	}
+	w.segments = segments
+
+	if len(w.segments) == 0 || w.pos.seq < w.segments[0].seq {
+		w.pos = position{}
+		if len(w.segments) > 0 {
+			w.pos.seq = w.segments[0].seq
+		}
+	}
+	return nil
+}
+
+// count returns the offsets of the valid records in a segment
+// and where they end.
+func (w *wal) count(seq uint64) ([]int64, int64, error) {
+	f, err := os.Open(w.path(seq))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	var offsets []int64
+	var off int64
+	r := bufio.NewReader(f)
+	for {
+		payload, err := w.readFrame(r)
+		if err != nil {
+			return offsets, off, nil
+		}
+		offsets = append(offsets, off)
+		off += frameHeaderSize + int64(len(payload))
+	}
+}
+
+func (w *wal) path(seq uint64) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%020d%s", seq, segmentExt))
+}
+
+// create creates a new active segment, w.mu must be held.
+func (w *wal) create(seq uint64) error {
+	f, err := os.OpenFile(w.path(seq), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	w.active = f
+	w.segments = append(w.segments, segment{seq: seq})
+	return nil
+}
+
+// append appends a record to the active segment.
+func (w *wal) append(payload []byte) error {
+	frame := make([]byte, frameHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(frame[0:], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(frame[4:], crc32.Checksum(payload, crcTable))
+	copy(frame[frameHeaderSize:], payload)
+	n := int64(len(frame))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+	if n > w.maxBytes {
+		w.dropped++
+		return fmt.Errorf("record of %d bytes is larger than MaxBytes", n)
+	}
+
+	active := &w.segments[len(w.segments)-1]
+	if active.size > 0 && active.size+n > w.segmentBytes {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+		active = &w.segments[len(w.segments)-1]
+	}
+
+	// make room by dropping the oldest segments
+	for w.bytes+n > w.maxBytes && len(w.segments) > 1 {
+		if err := w.dropOldest(); err != nil {
+			return err
+		}
+		active = &w.segments[len(w.segments)-1]
+	}
+
+	if _, err := w.active.Write(frame); err != nil {
+		// don't leave a partial frame behind
+		return multierr.Append(err, w.active.Truncate(active.size))
+	}
+
+	active.size += n
+	active.records++
+	w.bytes += n
+	w.appended++
+	w.dirty = true
+	w.signal()
+	return nil
+}
+
+// rotate seals the active segment and creates a new one, w.mu must be held.
+func (w *wal) rotate() error {
+	if err := multierr.Append(w.active.Sync(), w.active.Close()); err != nil {
+		return err
+	}
+	w.dirty = false
+	return w.create(w.segments[len(w.segments)-1].seq + 1)
+}
+
+// dropOldest deletes the oldest segment, even if it wasn't shipped yet.
+// w.mu must be held.
+func (w *wal) dropOldest() error {
+	s := w.segments[0]
+	if err := os.Remove(w.path(s.seq)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	w.segments = w.segments[1:]
+	w.bytes -= s.size
+
+	if w.pos.seq <= s.seq {
+		w.dropped += int64(s.records - w.pos.n)
+		w.pos = position{seq: w.segments[0].seq}
+		return w.saveCheckpoint()
+	}
+	return nil
+}
+
+func (w *wal) signal() {
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pending reports if there are records to ship, w.mu must be held.
+func (w *wal) pending() bool {
+	active := w.segments[len(w.segments)-1]
+	return w.pos.seq != active.seq || w.pos.off < active.size
+}
+
+// read returns up to max records starting at p and the position after them.
+// The rest of a segment is skipped, if it's corrupted.
+func (w *wal) read(p position, max int) ([][]byte, position, error) {
+	w.mu.Lock()
+	segments := append([]segment(nil), w.segments...)
+	w.mu.Unlock()
+
+	var payloads [][]byte
+	for i, s := range segments {
+		if s.seq < p.seq {
+			continue
+		}
+		if s.seq > p.seq {
+			p = position{seq: s.seq} // the previous segment was dropped
+		}
+
+		sealed := i < len(segments)-1
+		if p.off < s.size {
+			records, next, err := w.readSegment(p, s.size, max-len(payloads))
+			if err != nil {
+				return payloads, p, err
+			}
+			payloads = append(payloads, records...)
+
+			if next.off < s.size && len(payloads) < max {
+				// a record is corrupted, skip the rest of the segment
+				w.corrupt()
+				next.off = s.size
+			}
+			p = next
+		}
+
+		if len(payloads) >= max || !sealed {
+			return payloads, p, nil
+		}
+		p = position{seq: segments[i+1].seq}
+	}
+	return payloads, p, nil
+}
+
+// readSegment reads up to max records of segment p.seq between p and end.
+func (w *wal) readSegment(p position, end int64, max int) ([][]byte, position, error) {
+	f, err := os.Open(w.path(p.seq))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, position{seq: p.seq, off: end}, nil // dropped in the meantime
+		}
+		return nil, p, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(p.off, io.SeekStart); err != nil {
+		return nil, p, err
+	}
+	r := bufio.NewReader(io.LimitReader(f, end-p.off))
+
+	var payloads [][]byte
+	for len(payloads) < max {
+		payload, err := w.readFrame(r)
+		if err != nil {
+			break
+		}
+		payloads = append(payloads, payload)
+		p.off += frameHeaderSize + int64(len(payload))
+		p.n++
+	}
+	return payloads, p, nil
+}
+
+var errCorrupted = errors.New("corrupted record")
+
+// readFrame reads a frame and verifies its checksum.
+func (w *wal) readFrame(r io.Reader) ([]byte, error) {
+	var header [frameHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	length := binary.LittleEndian.Uint32(header[0:])
+	if int64(length) > w.maxBytes {
+		return nil, errCorrupted
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(header[4:]) {
+		return nil, errCorrupted
+	}
+	return payload, nil
+}
+
+func (w *wal) corrupt() {
+	w.mu.Lock()
+	w.corrupted++
+	w.mu.Unlock()
+}
+
+// position returns the acknowledged position.
+func (w *wal) position() position {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.pos
+}
+
+// ack acknowledges the records between from and to, unless from was
+// dropped in the meantime, and deletes the segments before to.
+func (w *wal) ack(from, to position, records int) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.pos != from {
+		return nil
+	}
+	w.pos = to
+	w.shipped += int64(records)
+
+	var err error
+	for len(w.segments) > 1 && w.segments[0].seq < to.seq {
+		s := w.segments[0]
+		if rerr := os.Remove(w.path(s.seq)); rerr != nil && !os.IsNotExist(rerr) {
+			err = multierr.Append(err, rerr)
+		}
+		w.segments = w.segments[1:]
+		w.bytes -= s.size
+	}
+	return multierr.Append(err, w.saveCheckpoint())
+}
+
+// saveCheckpoint saves w.pos, w.mu must be held.
+func (w *wal) saveCheckpoint() error {
+	path := filepath.Join(w.dir, checkpointName)
+	tmp := path + ".tmp"
+	data := fmt.Sprintf("%d %d\n", w.pos.seq, w.pos.off)
+	if err := ioutil.WriteFile(tmp, []byte(data), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (w *wal) loadCheckpoint() (position, error) {
+	data, err := ioutil.ReadFile(filepath.Join(w.dir, checkpointName))
+	if err != nil {
+		return position{}, err
+	}
+
+	var p position
+	if _, err := fmt.Sscanf(string(data), "%d %d\n", &p.seq, &p.off); err != nil {
+		return position{}, err
+	}
+	return p, nil
+}
+
+// sync commits the active segment to disk.
+func (w *wal) sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed || !w.dirty {
+		return nil
+	}
+	w.dirty = false
+	return w.active.Sync()
+}
+
+func (w *wal) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return multierr.Combine(w.active.Sync(), w.active.Close(), w.saveCheckpoint())
+}
@@ -0,0 +1,95 @@
+package spool
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestWALFrames(t *testing.T) {
+	w, err := openWAL(t.TempDir(), 1<<20, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.close()
+
+	for _, p := range []string{"a", "bb", "ccc"} {
+		if err := w.append([]byte(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	from := w.position()
+	payloads, to, err := w.read(from, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := [][]byte{[]byte("a"), []byte("bb")}; !reflect.DeepEqual(payloads, expect) {
+		t.Errorf("expected %q, got %q", expect, payloads)
+	}
+	if to.off != 2*frameHeaderSize+3 || to.n != 2 {
+		t.Errorf("unexpected position %+v", to)
+	}
+
+	if err := w.ack(from, to, len(payloads)); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := w.loadCheckpoint(); err != nil || p.seq != to.seq || p.off != to.off {
+		t.Errorf("expected checkpoint %+v, got %+v, %v", to, p, err)
+	}
+
+	payloads, _, _ = w.read(to, 10)
+	if expect := [][]byte{[]byte("ccc")}; !reflect.DeepEqual(payloads, expect) {
+		t.Errorf("expected %q, got %q", expect, payloads)
+	}
+}
+
+func TestCorruptedSegment(t *testing.T) {
+	dir := t.TempDir()
+	core, obs := observer.New(zapcore.InfoLevel)
+	remote := &remoteCore{Core: core, down: true}
+
+	s, err := testConfig(dir).Build(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.New(s)
+	logger.Info("1")
+	logger.Info("2")
+	s.Close()
+
+	// simulate a torn write
+	path := filepath.Join(dir, "00000000000000000001.wal")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{42, 0, 0, 0, 1, 2})
+	f.Close()
+
+	// and a flipped bit in another segment
+	second := filepath.Join(dir, "00000000000000000003.wal")
+	if err := os.WriteFile(second, []byte{1, 0, 0, 0, 1, 2, 3, 4, 'x'}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	remote.setDown(false)
+	s, err = testConfig(dir).Build(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	waitShipped(t, s, 2)
+	if msgs, expect := messages(obs), []string{"1", "2"}; !reflect.DeepEqual(msgs, expect) {
+		t.Errorf("expected %v, got %v", expect, msgs)
+	}
+	if st := s.Stats(); st.Corrupted != 2 {
+		t.Errorf("expected two corrupted segments, got %+v", st)
+	}
+}
@@ -1,23 +1,248 @@
 package log
 
+import (
+	"fmt"
+	"io"
+	stdlog "log"
+	"os"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
 // funcs from std/log package
 
+// These flags are the same as in std/log. They are kept for compatibility,
+// but have no effect on the output. Timestamps and callers are added by
+// the zap encoder.
+const (
+	Ldate         = stdlog.Ldate
+	Ltime         = stdlog.Ltime
+	Lmicroseconds = stdlog.Lmicroseconds
+	Llongfile     = stdlog.Llongfile
+	Lshortfile    = stdlog.Lshortfile
+	LUTC          = stdlog.LUTC
+	Lmsgprefix    = stdlog.Lmsgprefix
+	LstdFlags     = stdlog.LstdFlags
+)
+
+// Logger mirrors the Logger from std/log, but logs through zap.
+// Print logs at info level, Fatal at fatal level and Panic at panic level.
+type Logger struct {
+	mu     sync.RWMutex
+	prefix string
+	flag   int
+	out    io.Writer
+	logger *zap.Logger // nil for std, which uses the default logger until SetOutput
+}
+
+// std is used by the package level funcs
+var std = &Logger{flag: LstdFlags, out: os.Stderr}
+
+// New creates a new Logger that writes human-readable logs to out.
+// The prefix is prepended to every message. The flag argument is kept
+// for compatibility only.
+func New(out io.Writer, prefix string, flag int) *Logger {
+	return &Logger{
+		prefix: prefix,
+		flag:   flag,
+		out:    out,
+		logger: newWriterLogger(out),
+	}
+}
+
+// newWriterLogger returns a logger that writes to out, similar to the default logger.
+func newWriterLogger(out io.Writer) *zap.Logger {
+	encoderConfig := NewDevelopmentEncoderConfig()
+	encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder // out is not necessarily a terminal
+
+	core := zapcore.NewCore(
+		zapcore.NewConsoleEncoder(encoderConfig),
+		zapcore.AddSync(out),
+		zap.DebugLevel)
+
+	return zap.New(core,
+		zap.AddCaller(),
+		zap.AddCallerSkip(1),
+	)
+}
+
+// SetOutput sets the output destination for the logger. For the standard
+// logger it only applies to the std/log compatible funcs, like Print and
+// Fatalln, the default logger and its cores are left alone.
+func (l *Logger) SetOutput(w io.Writer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.out = w
+	l.logger = newWriterLogger(w)
+}
+
+// Writer returns the output destination for the logger.
+// For the standard logger this is os.Stderr, unless changed with SetOutput.
+func (l *Logger) Writer() io.Writer {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.out
+}
+
+// SetPrefix sets the prefix prepended to every message.
+func (l *Logger) SetPrefix(prefix string) {
+	l.mu.Lock()
+	l.prefix = prefix
+	l.mu.Unlock()
+}
+
+// Prefix returns the prefix for the logger.
+func (l *Logger) Prefix() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.prefix
+}
+
+// SetFlags sets the output flags for the logger. Flags have no effect.
+func (l *Logger) SetFlags(flag int) {
+	l.mu.Lock()
+	l.flag = flag
+	l.mu.Unlock()
+}
+
+// Flags returns the output flags for the logger.
+func (l *Logger) Flags() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.flag
+}
+
+// Output logs s at info level. Calldepth is the count of the number
+// of frames to skip when computing the caller, a value of 1 will
+// use the caller of Output.
+func (l *Logger) Output(calldepth int, s string) error {
+	l.output(calldepth, zap.InfoLevel, s)
+	return nil
+}
+
+// output logs msg with prefix. Calldepth is counted from the caller of output.
+func (l *Logger) output(calldepth int, level zapcore.Level, msg string) {
+	l.mu.RLock()
+	logger := l.logger
+	prefix := l.prefix
+	l.mu.RUnlock()
+
+	if logger == nil {
+		logger = defaultLogger()
+	}
+
+	// the logger already skips one caller
+	logger = logger.WithOptions(zap.AddCallerSkip(calldepth))
+
+	if ce := logger.Check(level, prefix+msg); ce != nil {
+		ce.Write()
+	}
+}
+
+func (l *Logger) Print(v ...interface{}) {
+	l.output(1, zap.InfoLevel, fmt.Sprint(v...))
+}
+
+func (l *Logger) Printf(format string, v ...interface{}) {
+	l.output(1, zap.InfoLevel, fmt.Sprintf(format, v...))
+}
+
+func (l *Logger) Println(v ...interface{}) {
+	l.output(1, zap.InfoLevel, sprintln(v...))
+}
+
+func (l *Logger) Fatal(v ...interface{}) {
+	l.output(1, zap.FatalLevel, fmt.Sprint(v...))
+}
+
+func (l *Logger) Fatalf(format string, v ...interface{}) {
+	l.output(1, zap.FatalLevel, fmt.Sprintf(format, v...))
+}
+
+func (l *Logger) Fatalln(v ...interface{}) {
+	l.output(1, zap.FatalLevel, sprintln(v...))
+}
+
+func (l *Logger) Panic(v ...interface{}) {
+	l.output(1, zap.PanicLevel, fmt.Sprint(v...))
+}
+
+func (l *Logger) Panicf(format string, v ...interface{}) {
+	l.output(1, zap.PanicLevel, fmt.Sprintf(format, v...))
+}
+
+func (l *Logger) Panicln(v ...interface{}) {
+	l.output(1, zap.PanicLevel, sprintln(v...))
+}
+
+// sprintln formats like fmt.Sprintln, without the trailing newline.
+func sprintln(v ...interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(v...), "\n")
+}
+
+// Default returns the standard logger used by the package level funcs.
+func Default() *Logger {
+	return std
+}
+
+// SetOutput sets the output destination for the standard logger, see
+// Logger.SetOutput. Use replaces the default logger instead.
+func SetOutput(w io.Writer) {
+	std.SetOutput(w)
+}
+
+// Writer returns the output destination for the standard logger.
+func Writer() io.Writer {
+	return std.Writer()
+}
+
+// SetPrefix sets the prefix for the standard logger. It is prepended to
+// messages logged with Print, Printf, Println, Panicln, Fatalln and Output.
+func SetPrefix(prefix string) {
+	std.SetPrefix(prefix)
+}
+
+// Prefix returns the prefix for the standard logger.
+func Prefix() string {
+	return std.Prefix()
+}
+
+// SetFlags sets the output flags for the standard logger. Flags have no effect.
+func SetFlags(flag int) {
+	std.SetFlags(flag)
+}
+
+// Flags returns the output flags for the standard logger.
+func Flags() int {
+	return std.Flags()
+}
+
+// Output logs s at info level, see Logger.Output.
+func Output(calldepth int, s string) error {
+	std.output(calldepth, zap.InfoLevel, s)
+	return nil
+}
+
 func Print(v ...interface{}) {
-	defaultSugarLogger.Info(v)
+	std.output(1, zap.InfoLevel, fmt.Sprint(v...))
 }
 
 func Printf(format string, v ...interface{}) {
-	defaultSugarLogger.Infof(format, v)
+	std.output(1, zap.InfoLevel, fmt.Sprintf(format, v...))
 }
 
 func Println(v ...interface{}) {
-	defaultSugarLogger.Info(v)
+	std.output(1, zap.InfoLevel, sprintln(v...))
 }
 
 func Panicln(v ...interface{}) {
-	defaultSugarLogger.Panic(v)
+	std.output(1, zap.PanicLevel, sprintln(v...))
 }
 
 func Fatalln(v ...interface{}) {
-	defaultSugarLogger.Fatal(v)
+	std.output(1, zap.FatalLevel, sprintln(v...))
 }
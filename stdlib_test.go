@@ -0,0 +1,89 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStdlib(t *testing.T) {
+	obs := setTestLogger()
+	defer SetPrefix("")
+
+	Printf("%v-%v", 1, 2)
+	Println("a", 1)
+	SetPrefix("prefix: ")
+	Print("a", "b")
+	Output(1, "output")
+
+	logs := obs.TakeAll()
+	expect := []string{"1-2", "a 1", "prefix: ab", "prefix: output"}
+	if len(logs) != len(expect) {
+		t.Fatalf("expected %v logs, got %v", len(expect), len(logs))
+	}
+
+	for i, l := range logs {
+		if l.Message != expect[i] {
+			t.Errorf("expected %q, got %q", expect[i], l.Message)
+		}
+		if filepath.Base(l.Caller.File) != "stdlib_test.go" {
+			t.Errorf("expected caller in stdlib_test.go, got %v", l.Caller.File)
+		}
+	}
+}
+
+func TestStdlibPanicln(t *testing.T) {
+	obs := setTestLogger()
+
+	defer func() {
+		if r := recover(); r != "oh no" {
+			t.Errorf("expected panic 'oh no', got %v", r)
+		}
+		if obs.Len() != 1 {
+			t.Errorf("expected a panic log")
+		}
+	}()
+
+	Panicln("oh", "no")
+}
+
+func TestNew(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := New(buf, "prefix: ", LstdFlags)
+
+	l.Printf("hello %v", "world")
+
+	out := buf.String()
+	if !strings.Contains(out, "INFO") || !strings.Contains(out, "prefix: hello world") {
+		t.Errorf("unexpected output %q", out)
+	}
+	if !strings.Contains(out, "stdlib_test.go") {
+		t.Errorf("expected caller in output, got %q", out)
+	}
+	if l.Writer() != buf || l.Prefix() != "prefix: " || l.Flags() != LstdFlags {
+		t.Errorf("unexpected logger settings")
+	}
+}
+
+func TestSetOutput(t *testing.T) {
+	obs := setTestLogger()
+	t.Cleanup(func() {
+		std.mu.Lock()
+		std.out, std.logger = os.Stderr, nil
+		std.mu.Unlock()
+	})
+
+	buf := &bytes.Buffer{}
+	SetOutput(buf)
+	Print("std")
+	Errorw("boom")
+
+	if !strings.Contains(buf.String(), "std") || strings.Contains(buf.String(), "boom") {
+		t.Errorf("expected only std/log funcs in output, got %q", buf.String())
+	}
+	if msgs := messages(obs); len(msgs) != 1 || msgs[0] != "boom" {
+		t.Errorf("expected default logger to be kept, got %v", msgs)
+	}
+}
@@ -5,97 +5,97 @@ import (
 )
 
 func DPanic(args ...interface{}) {
-	defaultSugarLogger.DPanic(args...)
+	defaultSugarLogger().DPanic(args...)
 }
 
 func DPanicf(template string, args ...interface{}) {
-	defaultSugarLogger.DPanicf(template, args...)
+	defaultSugarLogger().DPanicf(template, args...)
 }
 
 func DPanicw(msg string, keysAndValues ...interface{}) {
-	defaultSugarLogger.DPanicw(msg, keysAndValues...)
+	defaultSugarLogger().DPanicw(msg, keysAndValues...)
 }
 
 func Debug(args ...interface{}) {
-	defaultSugarLogger.Debug(args...)
+	defaultSugarLogger().Debug(args...)
 }
 
 func Debugf(template string, args ...interface{}) {
-	defaultSugarLogger.Debugf(template, args...)
+	defaultSugarLogger().Debugf(template, args...)
 }
 
 func Debugw(msg string, keysAndValues ...interface{}) {
-	defaultSugarLogger.Debugw(msg, keysAndValues...)
+	defaultSugarLogger().Debugw(msg, keysAndValues...)
 }
 
 func Error(args ...interface{}) {
-	defaultSugarLogger.Error(args...)
+	defaultSugarLogger().Error(args...)
 }
 
 func Errorf(template string, args ...interface{}) {
-	defaultSugarLogger.Errorf(template, args...)
+	defaultSugarLogger().Errorf(template, args...)
 }
 
 func Errorw(msg string, keysAndValues ...interface{}) {
-	defaultSugarLogger.Errorw(msg, keysAndValues...)
+	defaultSugarLogger().Errorw(msg, keysAndValues...)
 }
 
 func Fatal(args ...interface{}) {
-	defaultSugarLogger.Fatal(args...)
+	defaultSugarLogger().Fatal(args...)
 }
 
 func Fatalf(template string, args ...interface{}) {
-	defaultSugarLogger.Fatalf(template, args...)
+	defaultSugarLogger().Fatalf(template, args...)
 }
 
 func Fatalw(msg string, keysAndValues ...interface{}) {
-	defaultSugarLogger.Fatalw(msg, keysAndValues...)
+	defaultSugarLogger().Fatalw(msg, keysAndValues...)
 }
 
 func Info(args ...interface{}) {
-	defaultSugarLogger.Info(args...)
+	defaultSugarLogger().Info(args...)
 }
 
 func Infof(template string, args ...interface{}) {
-	defaultSugarLogger.Infof(template, args...)
+	defaultSugarLogger().Infof(template, args...)
 }
 
 func Infow(msg string, keysAndValues ...interface{}) {
-	defaultSugarLogger.Infow(msg, keysAndValues...)
+	defaultSugarLogger().Infow(msg, keysAndValues...)
 }
 
 func Named(name string) *zap.SugaredLogger {
-	return defaultSugarLogger.Named(name)
+	return defaultSugarLogger().Named(name)
 }
 
 func Panic(args ...interface{}) {
-	defaultSugarLogger.Panic(args...)
+	defaultSugarLogger().Panic(args...)
 }
 
 func Panicf(template string, args ...interface{}) {
-	defaultSugarLogger.Panicf(template, args...)
+	defaultSugarLogger().Panicf(template, args...)
 }
 
 func Panicw(msg string, keysAndValues ...interface{}) {
-	defaultSugarLogger.Panicw(msg, keysAndValues...)
+	defaultSugarLogger().Panicw(msg, keysAndValues...)
 }
 
 func Sync() error {
-	return defaultSugarLogger.Sync()
+	return defaultSugarLogger().Sync()
 }
 
 func Warn(args ...interface{}) {
-	defaultSugarLogger.Warn(args...)
+	defaultSugarLogger().Warn(args...)
 }
 
 func Warnf(template string, args ...interface{}) {
-	defaultSugarLogger.Warnf(template, args...)
+	defaultSugarLogger().Warnf(template, args...)
 }
 
 func Warnw(msg string, keysAndValues ...interface{}) {
-	defaultSugarLogger.Warnw(msg, keysAndValues...)
+	defaultSugarLogger().Warnw(msg, keysAndValues...)
 }
 
 func With(args ...interface{}) *zap.SugaredLogger {
-	return defaultSugarLogger.With(args...)
+	return defaultSugarLogger().With(args...)
 }
@@ -9,6 +9,7 @@ import (
 
 // Sampling is a convenience function that returns a zap.Option
 // which wraps a core with a sample policy.
+// See SamplerConfig for per-message sampling that keeps track of dropped entries.
 func Sampling(initial, thereafter int) zap.Option {
 	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
 		return zapcore.NewSampler(core, time.Second, initial, thereafter)